@@ -0,0 +1,253 @@
+// Molekula generates decoders and encoders of aerospike bins for Go types annotated
+// with the 'molekula:<bin name>' comment.
+// The comment annotates a single type, inside a grouped 'type (...)' declaration
+// it's written right above the annotated type.
+// The generated code depends on github.com/nikgalushko/molekula package.
+//
+// Options of the directive are separated by spaces, a leading option without a value is the bin name,
+// so 'molekula:profile,strict' is a short form of:
+//
+//	//molekula:bin=profile set=users strict numeric=widen func=DecodeProfile
+//
+// The set option groups types of a set, func names the decoder instead of Decode<Type><Bin>,
+// the encoder is named with Encode prefix instead of Decode. Unknown options are reported as errors.
+//
+// Bin names are at most 15 bytes of ASCII letters, digits, '_' and '-' like aerospike requires,
+// a bin name is claimed by a single type of the set and by a single field of a record.
+// Names of generated functions are derived from bin names in CamelCase, so bins like a_b and aB
+// of a record would get the same functions and are reported as errors.
+//
+// By default numbers are widened: any integer value is accepted for an integer type
+// if it fits, float64 is accepted for float32; numeric=strict requires exactly the same type.
+// Absent struct keys leave fields at zero values or tag defaults; the strict option
+// reports them as errors instead, except omitempty fields which are absent when empty.
+// Unknown keys are ignored unless unknown=reject is set or the struct has a rest field which
+// collects them. A required field can't be omitempty or have a default value. Default values are
+// allowed only for types which are stored as their built-in kinds, not for time.Duration or types
+// with their own codec methods.
+//
+// Struct fields are stored under lowercased names unless the 'molekula' tag says otherwise:
+//
+//	Name  string `molekula:"n,omitempty"`     // key "n", not encoded when empty
+//	ID    int64  `molekula:",required"`      // decoding fails when the key is absent
+//	Limit int    `molekula:"limit,default=10"` // absent key is decoded as 10
+//	Cache []byte `molekula:"-"`               // the field is skipped
+//	Extra map[string]interface{} `molekula:",rest"` // unknown keys are kept here
+//
+// Byte slices including named ones like net.IP are stored as blobs, fixed-length arrays as lists.
+// time.Duration is stored as integer nanoseconds, time.Time as unix nanoseconds unless a tag option
+// says otherwise: `molekula:"created,unix"` for unix seconds or `molekula:"created,rfc3339"` for a string.
+// Zero time doesn't fit unix nanoseconds, so it's stored as 0 and 0 is decoded as zero time.
+// Named types like 'type Level int' are stored as their built-in types. A named type annotated
+// with the 'molekula:enum' comment accepts only values of its constants declared in its package.
+//
+// Types which implement molekula.BinUnmarshaler and molekula.BinMarshaler or encoding.TextUnmarshaler
+// and encoding.TextMarshaler decode and encode themselves, text is stored as a string.
+// Encoders don't return errors, so an encoder of a value with MarshalText panics if it fails;
+// such encoders document it.
+//
+// Unexported fields are skipped unless the unexported option is set, it affects only
+// structs of the processed package. Structs of other packages which have only unexported fields
+// like time.Location can't be stored and are reported as errors.
+// Fields of embedded structs are promoted to the key space of the parent like in encoding/json,
+// an embedded struct tagged with a key is nested under the key instead.
+// Own fields of a struct with the same key are reported as errors.
+//
+// A struct annotated with the 'molekula:record' comment is a whole record: every field is stored
+// in its own bin named like a key of struct field, and <Type>FromBinMap and <Type>ToBinMap functions
+// convert the struct to aerospike.BinMap and back with codecs of the bins. Options of the directive
+// apply to codecs of all bins, tag options apply to bins like to keys. A bin of annotated type
+// like Profile below is decoded and encoded by functions of the type with its own options:
+//
+//	//molekula:record,strict
+//	type User struct {
+//		Name    string  `molekula:"name,required"`
+//		Profile Profile `molekula:"profile,omitempty"`
+//	}
+//
+// The ns and set options of a record generate <Type>Namespace and <Type>Set constants. A field tagged
+// with the key option is the user key of record, it's stored in its bin too; <Type>NewKey and <Type>KeyFor
+// functions build aerospike keys of the record from a struct or from a key value, they require the ns option:
+//
+//	//molekula:record ns=prod set=users
+//	type User struct {
+//		ID   int64 `molekula:"id,key"`
+//		Name string
+//	}
+//
+// The repo option of a record generates <Type>Repo with Get, Put, Delete and BatchGet methods which
+// decode and encode records. The repository works with the MolekulaClient interface which is
+// generated once per package; *aerospike.Client implements it, so does an in-memory fake in tests.
+//
+// Usage:
+//
+//	molekula [flags] [directory]
+//
+// The directory defaults to the current one, so under go:generate the
+// package of $GOFILE is processed:
+//
+//	//go:generate molekula
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/nikgalushko/molekula/internal/ast"
+	"github.com/nikgalushko/molekula/internal/gen"
+	"github.com/nikgalushko/molekula/internal/parser"
+	"github.com/nikgalushko/molekula/internal/query"
+)
+
+const suffix = "_molekula.go"
+
+var (
+	output = flag.String("output", "", "output file name; default <package>"+suffix)
+	client = flag.String("client", "github.com/aerospike/aerospike-client-go/v7", "import path of aerospike client package which is used by records")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of molekula:\n")
+	fmt.Fprintf(os.Stderr, "\tmolekula [flags] [directory]\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("molekula: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+		// go:generate runs a command in the directory of $GOFILE
+		if gofile := os.Getenv("GOFILE"); gofile != "" {
+			dir = filepath.Dir(gofile)
+		}
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkg, err := parser.Load(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	objects, diagnostics := parser.Parse(pkg)
+	if len(diagnostics) > 0 {
+		wd, _ := os.Getwd()
+		for _, d := range diagnostics {
+			// print paths relative to the working directory like the go command does
+			if rel, err := filepath.Rel(wd, d.Pos.Filename); err == nil {
+				d.Pos.Filename = rel
+			}
+			fmt.Fprintln(os.Stderr, d)
+		}
+		os.Exit(1)
+	}
+
+	src, err := generate(pkg.Name, objects)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outputName := *output
+	if outputName == "" {
+		outputName = pkg.Name + suffix
+	}
+	if !filepath.IsAbs(outputName) {
+		outputName = filepath.Join(dir, outputName)
+	}
+
+	err = os.WriteFile(outputName, src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns a formatted source of decoders and encoders for the objects.
+func generate(pkgName string, objects []parser.Object) ([]byte, error) {
+	file := gen.File{
+		Package: pkgName,
+		Codecs:  make([]gen.Codec, 0, len(objects)),
+		Client:  *client,
+	}
+
+	for _, o := range objects {
+		if o.Record {
+			file.Imports = append(file.Imports, o.Imports...)
+			file.Records = append(file.Records, record(o))
+			continue
+		}
+
+		// a type with own UnmarshalBin and MarshalBin methods gets only functions as well as
+		// pointers, interfaces and aliases which can't have methods
+		custom, ok := o.Type.(ast.Custom)
+
+		file.Imports = append(file.Imports, o.Imports...)
+		file.Codecs = append(file.Codecs, gen.Codec{
+			Name:    o.Name,
+			BinName: o.BinName,
+			Func:    o.Func,
+			Method:  o.Methods && (!ok || custom.Text),
+			Query:   query.Build(o),
+
+			StrictNumeric: o.StrictNumeric,
+			Strict:        o.Strict,
+			RejectUnknown: o.RejectUnknown,
+		})
+	}
+
+	return gen.Generate(file)
+}
+
+// record returns a record with a codec for every field of the struct
+func record(o parser.Object) gen.Record {
+	fields := o.Type.(ast.Struct).Fields
+	r := gen.Record{
+		Name:      o.Name,
+		Bins:      make([]gen.Bin, 0, len(fields)),
+		Namespace: o.Namespace,
+		Set:       o.Set,
+		Repo:      o.Repo,
+	}
+
+	for _, f := range fields {
+		if f.Key {
+			r.Key = f.Name
+		}
+
+		// promoted fields have only types of their values
+		typeName := f.TypeName
+		if typeName == "" {
+			typeName = f.Type.RawTypeName()
+		}
+
+		r.Bins = append(r.Bins, gen.Bin{
+			Codec: gen.Codec{
+				Name:    o.Name,
+				BinName: f.Alias,
+				Type:    typeName,
+				Query:   query.Build(parser.Object{Type: f.Type}),
+
+				StrictNumeric: o.StrictNumeric,
+				Strict:        o.Strict,
+				RejectUnknown: o.RejectUnknown,
+			},
+			Field:     f.Name,
+			OmitEmpty: f.OmitEmpty,
+			Required:  f.Required,
+			Default:   f.Default,
+		})
+	}
+
+	return r
+}
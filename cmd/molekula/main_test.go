@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/nikgalushko/molekula/internal/parser"
+)
+
+func TestGenerate(t *testing.T) {
+	prev := *client
+	*client = "github.com/nikgalushko/molekula/cmd/molekula/testdata/aerospike"
+	defer func() { *client = prev }()
+
+	dir := filepath.Join("testdata", "store")
+	pkg, err := parser.Load(dir)
+	require.NoError(t, err)
+
+	objects, diagnostics := parser.Parse(pkg)
+	require.Empty(t, diagnostics)
+
+	src, err := generate(pkg.Name, objects)
+	require.NoError(t, err)
+
+	output, err := filepath.Abs(filepath.Join(dir, pkg.Name+suffix))
+	require.NoError(t, err)
+
+	// the generated file is type-checked with the package without writing it to the testdata
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     dir,
+		Overlay: map[string][]byte{output: src},
+	}, ".")
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+
+	assert.Contains(t, pkgs[0].GoFiles, output)
+	for _, e := range pkgs[0].Errors {
+		t.Errorf("%s\n%s", e, src)
+	}
+
+	scope := pkgs[0].Types.Scope()
+	for _, name := range []string{"DecodeUsers", "EncodeProfileProfile", "CustomerFromBinMap", "NewCustomerRepo", "MolekulaClient"} {
+		assert.NotNil(t, scope.Lookup(name), "%s is not generated", name)
+	}
+}
@@ -0,0 +1,29 @@
+// Package aerospike is a stub of types of the aerospike client which are used by the generated code.
+package aerospike
+
+type BinMap map[string]interface{}
+
+type Error interface {
+	error
+}
+
+type Key struct {
+	Namespace string
+	Set       string
+	Value     interface{}
+}
+
+func NewKey(namespace string, setName string, key interface{}) (*Key, Error) {
+	return &Key{Namespace: namespace, Set: setName, Value: key}, nil
+}
+
+type (
+	BasePolicy  struct{}
+	WritePolicy struct{}
+	BatchPolicy struct{}
+)
+
+type Record struct {
+	Key  *Key
+	Bins BinMap
+}
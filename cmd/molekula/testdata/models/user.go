@@ -0,0 +1,14 @@
+package models
+
+type User struct {
+	Name string `molekula:"n"`
+	Age  int
+}
+
+//molekula:enum
+type Status string
+
+const (
+	Active  Status = "active"
+	Blocked Status = "blocked"
+)
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"net"
+	"time"
+
+	legacy "github.com/nikgalushko/molekula/cmd/molekula/testdata/legacy/models"
+	"github.com/nikgalushko/molekula/cmd/molekula/testdata/models"
+)
+
+//molekula:bin=users set=accounts numeric=strict func=DecodeUsers
+type Users []models.User
+
+//molekula:profile,strict unknown=reject
+type Profile struct {
+	Name    string                 `molekula:",required"`
+	Tags    []string               `molekula:",omitempty"`
+	Limit   int8                   `molekula:",default=10"`
+	Status  models.Status          `molekula:"status"`
+	Seen    time.Time              `molekula:"seen,unix"`
+	TTL     time.Duration          `molekula:"ttl"`
+	Address net.IP                 `molekula:"addr"`
+	Any     interface{}            `molekula:"any"`
+	Scores  map[string]*int64      `molekula:"scores"`
+	Point   [3]float64             `molekula:"point"`
+	Empty   struct{}               `molekula:"empty"`
+	Legacy  legacy.User            `molekula:"legacy"`
+	Extra   map[string]interface{} `molekula:",rest"`
+}
+
+//molekula:ptr
+type Ptr *int
+
+//molekula:anything
+type Anything interface{}
+
+//molekula:scores
+type Scores = map[string]int
+
+//molekula:record ns=prod set=customers strict repo
+type Customer struct {
+	ID      int32   `molekula:"id,key"`
+	Name    string  `molekula:",required"`
+	Users   Users   `molekula:"users"`
+	Profile Profile `molekula:"profile,omitempty"`
+}
+
+// Client is declared by the package itself, it doesn't clash with the generated code
+type Client struct{}
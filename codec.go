@@ -0,0 +1,25 @@
+package molekula
+
+import "fmt"
+
+// BinUnmarshaler is implemented by types which decode themselves from a value of bin.
+// Generated decoders call it instead of decoding the structure of a type.
+type BinUnmarshaler interface {
+	UnmarshalBin(data interface{}) error
+}
+
+// BinMarshaler is implemented by types which encode themselves to a value of bin.
+// Generated encoders call it instead of encoding the structure of a type.
+type BinMarshaler interface {
+	MarshalBin() interface{}
+}
+
+// Text returns a result of encoding.TextMarshaler as a string which is stored in a bin.
+// Encoders can't fail, so it panics if the value can't be marshaled.
+func Text(text []byte, err error) string {
+	if err != nil {
+		panic(fmt.Sprintf("molekula: marshal text: %v", err))
+	}
+
+	return string(text)
+}
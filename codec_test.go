@@ -0,0 +1,15 @@
+package molekula
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "127.0.0.1", Text([]byte("127.0.0.1"), nil))
+	assert.PanicsWithValue(t, "molekula: marshal text: invalid address", func() {
+		Text(nil, errors.New("invalid address"))
+	})
+}
@@ -0,0 +1,128 @@
+// Package molekula contains types which are used by code generated with cmd/molekula.
+package molekula
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DecodeError is returned by a generated decoder when a bin value doesn't match a Go type
+type DecodeError struct {
+	// Bin is aerospikes' bin name
+	Bin string
+	// Path is a path to the failed value like config["eu"].Users[3].ID
+	Path string
+	// Expected is a Go type of the failed value
+	Expected string
+	// Got is a dynamic type of the failed value
+	Got string
+	// Key is true if the failed value is a key of map
+	Key bool
+	// Missing is true if the value is required but absent
+	Missing bool
+	// Unknown is a sorted list of keys of the struct which don't belong to any field
+	Unknown []string
+	// Value is a value of enum which isn't one of declared constants
+	Value string
+	// Err is an error of parsing the value which has the expected dynamic type but an invalid content
+	Err error
+}
+
+// NewDecodeError returns DecodeError for the value which doesn't match the expected type.
+// The path is a format which is formatted with args.
+func NewDecodeError(bin, expected string, got interface{}, path string, args ...interface{}) *DecodeError {
+	return &DecodeError{
+		Bin:      bin,
+		Path:     fmt.Sprintf(path, args...),
+		Expected: expected,
+		Got:      fmt.Sprintf("%T", got),
+	}
+}
+
+// NewKeyError returns DecodeError for the key of map which doesn't match the expected type.
+// The path is a format which is formatted with args.
+func NewKeyError(bin, expected string, got interface{}, path string, args ...interface{}) *DecodeError {
+	err := NewDecodeError(bin, expected, got, path, args...)
+	err.Key = true
+
+	return err
+}
+
+// NewLengthError returns DecodeError for the list which length doesn't match the length of expected array.
+// The path is a format which is formatted with args.
+func NewLengthError(bin, expected string, length int, path string, args ...interface{}) *DecodeError {
+	err := NewDecodeError(bin, expected, nil, path, args...)
+	err.Got = fmt.Sprintf("[]interface{} of length %d", length)
+
+	return err
+}
+
+// NewParseError returns DecodeError for the value which can't be parsed as the expected type.
+// The path is a format which is formatted with args.
+func NewParseError(bin, expected string, got interface{}, err error, path string, args ...interface{}) *DecodeError {
+	e := NewDecodeError(bin, expected, got, path, args...)
+	e.Err = err
+
+	return e
+}
+
+// NewEnumError returns DecodeError for the value of enum which isn't one of declared constants.
+// The path is a format which is formatted with args.
+func NewEnumError(bin, expected string, value interface{}, path string, args ...interface{}) *DecodeError {
+	err := NewDecodeError(bin, expected, value, path, args...)
+	err.Value = fmt.Sprintf("%#v", value)
+
+	return err
+}
+
+// NewMissingError returns DecodeError for the required value which is absent.
+// The path is a format which is formatted with args.
+func NewMissingError(bin, expected string, path string, args ...interface{}) *DecodeError {
+	err := NewDecodeError(bin, expected, nil, path, args...)
+	err.Missing = true
+
+	return err
+}
+
+// NewUnknownError returns DecodeError for the struct which contains keys that don't belong to any field.
+// The path is a format which is formatted with args.
+func NewUnknownError(bin, expected string, keys []interface{}, path string, args ...interface{}) *DecodeError {
+	err := NewDecodeError(bin, expected, nil, path, args...)
+	for _, key := range keys {
+		err.Unknown = append(err.Unknown, fmt.Sprintf("%#v", key))
+	}
+	sort.Strings(err.Unknown)
+
+	return err
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Unknown) > 0 {
+		return fmt.Sprintf("molekula: bin %q: %s: unexpected keys of %s: %s", e.Bin, e.Path, e.Expected, strings.Join(e.Unknown, ", "))
+	}
+
+	if e.Value != "" {
+		return fmt.Sprintf("molekula: bin %q: %s: value %s is not one of declared values of %s", e.Bin, e.Path, e.Value, e.Expected)
+	}
+
+	if e.Err != nil {
+		return fmt.Sprintf("molekula: bin %q: %s: invalid value of type %s: %v", e.Bin, e.Path, e.Expected, e.Err)
+	}
+
+	if e.Missing {
+		return fmt.Sprintf("molekula: bin %q: %s: missing required value of type %s", e.Bin, e.Path, e.Expected)
+	}
+
+	value := "value"
+	if e.Key {
+		value = "key"
+	}
+
+	return fmt.Sprintf("molekula: bin %q: %s: expected %s of type %s, got %s", e.Bin, e.Path, value, e.Expected, e.Got)
+}
+
+// Unwrap returns the error of parsing the value if any
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,52 @@
+package molekula
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeError_Error(t *testing.T) {
+	err := NewDecodeError("config", "int64", "1", "config[%#v].Users[%d].ID", "eu", 3)
+	assert.Equal(t, &DecodeError{
+		Bin:      "config",
+		Path:     `config["eu"].Users[3].ID`,
+		Expected: "int64",
+		Got:      "string",
+	}, err)
+	assert.EqualError(t, err, `molekula: bin "config": config["eu"].Users[3].ID: expected value of type int64, got string`)
+
+	err = NewKeyError("config", "string", 1, "config[%#v]", 1)
+	assert.EqualError(t, err, `molekula: bin "config": config[1]: expected key of type string, got int`)
+
+	err = NewMissingError("config", "string", "config[%#v].Name", "eu")
+	assert.Equal(t, &DecodeError{
+		Bin:      "config",
+		Path:     `config["eu"].Name`,
+		Expected: "string",
+		Got:      "<nil>",
+		Missing:  true,
+	}, err)
+	assert.EqualError(t, err, `molekula: bin "config": config["eu"].Name: missing required value of type string`)
+
+	err = NewLengthError("point", "[3]float64", 2, "point")
+	assert.EqualError(t, err, `molekula: bin "point": point: expected value of type [3]float64, got []interface{} of length 2`)
+
+	parseErr := errors.New("bad month")
+	err = NewParseError("event", "time.Time", "2021-13-01", parseErr, "event.At")
+	assert.Equal(t, "string", err.Got)
+	assert.ErrorIs(t, err, parseErr)
+	assert.EqualError(t, err, `molekula: bin "event": event.At: invalid value of type time.Time: bad month`)
+
+	err = NewEnumError("user", "models.Status", "deleted", "user.Status")
+	assert.Equal(t, `"deleted"`, err.Value)
+	assert.EqualError(t, err, `molekula: bin "user": user.Status: value "deleted" is not one of declared values of models.Status`)
+
+	err = NewUnknownError("config", "Value", []interface{}{"z", 1, "a"}, "config[%#v]", "eu")
+	assert.Equal(t, []string{`"a"`, `"z"`, "1"}, err.Unknown)
+	assert.EqualError(t, err, `molekula: bin "config": config["eu"]: unexpected keys of Value: "a", "z", 1`)
+
+	err = NewDecodeError("users", "[]models.User", nil, "users")
+	assert.EqualError(t, err, `molekula: bin "users": users: expected value of type []models.User, got <nil>`)
+}
@@ -1,8 +1,17 @@
 module github.com/nikgalushko/molekula
 
-go 1.15
+go 1.22.0
 
 require (
 	github.com/stretchr/testify v1.7.0
-	github.com/traefik/yaegi v0.9.17
+	github.com/traefik/yaegi v0.16.1
+	golang.org/x/tools v0.30.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
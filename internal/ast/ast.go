@@ -27,17 +27,30 @@ type StructField struct {
 	// Alias is a aerospikes' name of fiels
 	Alias string
 	Type  Type
+	// OmitEmpty is true if the field isn't encoded when it has an empty value
+	OmitEmpty bool
+	// Required is true if decoding fails when the field is absent
+	Required bool
+	// Default is a Go literal of value which is decoded when the field is absent
+	Default string
+	// Rest is true if the field is map[string]interface{} which collects keys unknown to other fields
+	Rest bool
+	// Key is true if the field of record is the user key of aerospike record, it's stored in its bin too
+	Key bool
+	// TypeName is a Go type of own field of record as it's declared like Users,
+	// Type of a named slice or map describes only its underlying type like []models.User
+	TypeName string
 }
 
-// Map is a mapping a Key to a Value
+// Map is a mapping a Key to a Value, the Key is BuiltIn or Named
 type Map struct {
-	Key   BuiltIn
+	Key   Type
 	Value Type
 }
 
 // RawTypeName returns a full type of map like map[int]string
 func (m Map) RawTypeName() string {
-	return fmt.Sprintf("map[%s]%s", m.Key, m.Value.RawTypeName())
+	return fmt.Sprintf("map[%s]%s", m.Key.RawTypeName(), m.Value.RawTypeName())
 }
 
 // Array is not a array but slice of elements
@@ -50,6 +63,78 @@ func (a Array) RawTypeName() string {
 	return fmt.Sprintf("[]%s", a.Element.RawTypeName())
 }
 
+// FixedArray is an array of Len elements
+type FixedArray struct {
+	Element Type
+	Len     int64
+}
+
+// RawTypeName returns a full type of array like [3]float64
+func (a FixedArray) RawTypeName() string {
+	return fmt.Sprintf("[%d]%s", a.Len, a.Element.RawTypeName())
+}
+
+// Blob is a byte slice which is stored as a blob: []byte or a named type like net.IP
+type Blob string
+
+// RawTypeName returns a type name of byte slice: []byte, net.IP etc.
+func (b Blob) RawTypeName() string {
+	return string(b)
+}
+
+// Named is a named type which Underlying type is built-in: type Level int
+type Named struct {
+	Name       string
+	Underlying BuiltIn
+	// Enum is a list of Go literals of declared constants if the type is annotated as enum
+	Enum []string
+}
+
+// RawTypeName returns a type name: Level, models.Status etc.
+func (n Named) RawTypeName() string {
+	return n.Name
+}
+
+// Time is time.Time which is stored in a bin in the Format: unix, unixnano or rfc3339
+type Time struct {
+	Format string
+}
+
+// RawTypeName returns time.Time
+func (t Time) RawTypeName() string {
+	return "time.Time"
+}
+
+// Duration is time.Duration which is stored in a bin as integer nanoseconds
+type Duration struct{}
+
+// RawTypeName returns time.Duration
+func (d Duration) RawTypeName() string {
+	return "time.Duration"
+}
+
+// Custom is a named type which decodes and encodes itself with UnmarshalBin and MarshalBin methods,
+// or with UnmarshalText and MarshalText methods if Text is true
+type Custom struct {
+	Name string
+	Text bool
+}
+
+// RawTypeName returns a type name: Money, net.IP etc.
+func (c Custom) RawTypeName() string {
+	return c.Name
+}
+
+// Pointer is a pointer to an Element
+type Pointer struct {
+	Element Type
+}
+
+// RawTypeName returns a full type of pointer like *int
+func (p Pointer) RawTypeName() string {
+	return fmt.Sprintf("*%s", p.Element.RawTypeName())
+}
+
 // BuiltIn is built-in Go type: int, uint, string, float64, rune etc.
 type BuiltIn string
 
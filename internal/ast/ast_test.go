@@ -60,6 +60,40 @@ func TestType_RawTypeName(t *testing.T) {
 				},
 			},
 		},
+		"fixed array of slices": {
+			RawTypeName: "[3][]int",
+			T: FixedArray{
+				Element: Array{Element: BuiltIn("int")},
+				Len:     3,
+			},
+		},
+		"slice of blobs": {
+			RawTypeName: "[]net.IP",
+			T:           Array{Element: Blob("net.IP")},
+		},
+		"map of times": {
+			RawTypeName: "map[string]time.Time",
+			T:           Map{Key: BuiltIn("string"), Value: Time{Format: "rfc3339"}},
+		},
+		"slice of durations": {
+			RawTypeName: "[]time.Duration",
+			T:           Array{Element: Duration{}},
+		},
+		"slice of custom types": {
+			RawTypeName: "[]*models.Money",
+			T:           Array{Element: Pointer{Element: Custom{Name: "models.Money"}}},
+		},
+		"map of pointers to slice of pointers": {
+			RawTypeName: "map[string]*[]*int",
+			T: Map{
+				Key: BuiltIn("string"),
+				Value: Pointer{
+					Element: Array{
+						Element: Pointer{Element: BuiltIn("int")},
+					},
+				},
+			},
+		},
 	}
 
 	for title, tt := range tests {
@@ -0,0 +1,163 @@
+package gen
+
+import "text/template"
+
+// keys of named types are encoded as their built-in kinds
+const encodeMap = `
+ret_{{.Index}} := make(map[interface{}]interface{}, len({{template "EINPUT" .}}))
+for key_{{.Index}}, value_{{.Index}} := range {{template "EINPUT" .}} {
+	{{with .Next}}{{template "E" .}}{{end}}
+	{{if .KeyKind}}
+		ret_{{.Index}}[{{.KeyKind}}(key_{{.Index}})] = ret_{{inc .Index}}
+	{{else}}
+		ret_{{.Index}}[key_{{.Index}}] = ret_{{inc .Index}}
+	{{end}}
+}
+`
+
+// fixed arrays are encoded as slices
+const encodeArray = `
+ret_{{.Index}} := make([]interface{}, 0, len({{template "EINPUT" .}}))
+for _, value_{{.Index}} := range {{template "EINPUT" .}} {
+	{{with .Next}}{{template "E" .}}{{end}}
+	ret_{{.Index}} = append(ret_{{.Index}}, ret_{{inc .Index}})
+}
+`
+
+// named types are encoded as their built-in kinds
+const encodeBuiltin = `
+ret_{{.Index}} := {{kind .}}({{template "EINPUT" .}})
+`
+
+// keys of the rest field are encoded first, so they can't overwrite other fields.
+// The input of a struct without fields is discarded, because a nested input is a variable which must be used.
+const encodeStruct = `
+ret_{{.Index}} := make(map[interface{}]interface{}, {{len .Fields}})
+{{if not .Fields}}
+	_ = {{template "EINPUT" .}}
+{{end}}
+{{with .RestField}}
+	for key_{{$.Index}}, value_{{$.Index}} := range {{template "EINPUT" $}}.{{.Name}} {
+		ret_{{$.Index}}[key_{{$.Index}}] = value_{{$.Index}}
+	}
+{{end}}
+{{range $val := .Fields}}
+{{if $val.Rest}}
+{{else if $val.OmitEmpty}}
+	if value_{{$.Index}} := {{template "EINPUT" $}}.{{$val.Name}}; {{notEmpty $val (print "value_" $.Index)}} {
+		{{template "E" $val}}
+		ret_{{$.Index}}[{{quote $val.Alias}}] = ret_{{$val.Index}}
+	}
+{{else}}
+	{
+		value_{{$.Index}} := {{template "EINPUT" $}}.{{$val.Name}}
+		{{template "E" $val}}
+		ret_{{$.Index}}[{{quote $val.Alias}}] = ret_{{$val.Index}}
+	}
+{{end}}
+{{end}}
+`
+
+const encodeCustom = `
+{{if .IsText}}
+	ret_{{.Index}} := molekula.Text({{template "EINPUT" .}}.MarshalText())
+{{else}}
+	ret_{{.Index}} := {{template "EINPUT" .}}.MarshalBin()
+{{end}}
+`
+
+const encodeBlob = `
+ret_{{.Index}} := []byte({{template "EINPUT" .}})
+`
+
+// time is encoded in RFC 3339 format with nanoseconds or as unix seconds or nanoseconds
+// zero time is out of range of unix nanoseconds, so it's encoded as 0
+const encodeTime = `
+{{if eq .TimeFormat "rfc3339"}}
+	ret_{{.Index}} := {{template "EINPUT" .}}.Format(time.RFC3339Nano)
+{{else if eq .TimeFormat "unix"}}
+	ret_{{.Index}} := {{template "EINPUT" .}}.Unix()
+{{else}}
+	var ret_{{.Index}} int64
+	if !{{template "EINPUT" .}}.IsZero() {
+		ret_{{.Index}} = {{template "EINPUT" .}}.UnixNano()
+	}
+{{end}}
+`
+
+const encodeDuration = `
+ret_{{.Index}} := int64({{template "EINPUT" .}})
+`
+
+// nil pointer is encoded as nil value
+const encodePointer = `
+var ret_{{.Index}} interface{}
+if {{template "EINPUT" .}} != nil {
+	value_{{.Index}} := *{{template "EINPUT" .}}
+	{{with .Next}}{{template "E" .}}{{end}}
+	ret_{{.Index}} = ret_{{inc .Index}}
+}
+`
+
+// encodeInput is a name of variable which holds a value to encode
+const encodeInput = `{{if .IsTop}}v{{else}}value_{{sub .Index}}{{end}}`
+
+const encodeMain = `
+{{if .IsMap}}
+	{{template "EMAP" .}}
+{{else if or .IsArray .IsFixedArray}}
+	{{template "EARR" .}}
+{{else if .IsBuiltin}}
+	{{template "EBUILTIN" .}}
+{{else if .IsBlob}}
+	{{template "EBLOB" .}}
+{{else if .IsCustom}}
+	{{template "ECUSTOM" .}}
+{{else if .IsTime}}
+	{{template "ETIME" .}}
+{{else if .IsDuration}}
+	{{template "EDURATION" .}}
+{{else if .IsStruct}}
+	{{template "ESTRUCT" .}}
+{{else if .IsPointer}}
+	{{template "EPTR" .}}
+{{else}}
+	{{unsupported .}}
+{{end}}
+`
+
+const encoder = `
+// {{.Encoder}} encodes {{.Type}} to a value of the '{{.BinName}}' bin.
+{{- if .Root.HasText}}
+// It panics if MarshalText of a value fails, because encoders don't return errors.
+{{- end}}
+func {{.Encoder}}(v {{.Type}}) interface{} {
+	{{with .Root}}{{template "E" .}}{{end}}
+	return ret_0
+}
+{{if .Method}}
+{{$recv := receiver .Name}}
+// MarshalBin encodes {{.Name}} to a value of the '{{.BinName}}' bin.
+{{- if .Root.HasText}}
+// It panics if MarshalText of a value fails.
+{{- end}}
+func ({{$recv}} {{.Name}}) MarshalBin() interface{} {
+	return {{.Encoder}}({{$recv}})
+}
+{{end}}
+`
+
+func init() {
+	template.Must(tmpl.New("ENCODER").Parse(encoder))
+	template.Must(tmpl.New("EINPUT").Parse(encodeInput))
+	template.Must(tmpl.New("EMAP").Parse(encodeMap))
+	template.Must(tmpl.New("EARR").Parse(encodeArray))
+	template.Must(tmpl.New("EBUILTIN").Parse(encodeBuiltin))
+	template.Must(tmpl.New("EBLOB").Parse(encodeBlob))
+	template.Must(tmpl.New("ECUSTOM").Parse(encodeCustom))
+	template.Must(tmpl.New("ETIME").Parse(encodeTime))
+	template.Must(tmpl.New("EDURATION").Parse(encodeDuration))
+	template.Must(tmpl.New("ESTRUCT").Parse(encodeStruct))
+	template.Must(tmpl.New("EPTR").Parse(encodePointer))
+	template.Must(tmpl.New("E").Parse(encodeMain))
+}
@@ -2,11 +2,87 @@ package gen
 
 import (
 	"bytes"
+	"fmt"
+	"go/format"
+	goparser "go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
 	"text/template"
+	"unicode"
 
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/nikgalushko/molekula/internal/parser"
 	"github.com/nikgalushko/molekula/internal/query"
 )
 
+// File is a description of a generated file
+type File struct {
+	// Package is a package name of the generated file
+	Package string
+	// Imports is a list of packages which are used by types of codecs
+	Imports []parser.Import
+	Codecs  []Codec
+	Records []Record
+	// Client is an import path of aerospike client package, it's imported only by files with records
+	Client string
+}
+
+// Codec is a description of functions which are generated for a single bin
+type Codec struct {
+	// Name is a name of Go type, codecs of record bins are named after the record
+	Name string
+	// Type is a Go type of the bin value, it's Name if empty
+	Type string
+	// Func is a name of decoder which starts with Decode, the encoder is named with Encode prefix instead.
+	// Functions are named Decode<Name><BinName> and Encode<Name><BinName> if it's empty.
+	Func string
+	// BinName is aerospikes' bin name of the type
+	BinName string
+	// Method is true if UnmarshalBin and MarshalBin methods should be generated for the type.
+	// Methods are allowed only for defined types of the package of generated file which aren't pointers or interfaces.
+	Method bool
+	// StrictNumeric is true if numbers are decoded only from values of exactly the same type
+	StrictNumeric bool
+	// Strict is true if absent fields of structs are reported as errors
+	Strict bool
+	// RejectUnknown is true if keys of structs which don't belong to any field are reported as errors
+	RejectUnknown bool
+	Query         query.Query
+}
+
+// Record is a description of functions which convert a struct to bins of a record and back
+type Record struct {
+	// Name is a name of Go struct
+	Name string
+	// Bins are codecs of the struct fields, every field is stored in its own bin.
+	// A bin of type which has its own codec in the file is decoded and encoded by functions of that codec,
+	// so options of the type apply to the bin.
+	Bins []Bin
+	// Namespace and Set are the location of records, constants are generated for them if they aren't empty
+	Namespace string
+	Set       string
+	// Key is a name of the field which is the user key of record, key functions are generated if it isn't empty
+	Key string
+	// Repo is true if a repository of records which uses the MolekulaClient interface should be generated
+	Repo bool
+}
+
+// Bin is a codec of record field
+type Bin struct {
+	Codec
+	// Field is a name of struct field which may be a path of promoted field like Meta.Version
+	Field string
+	// OmitEmpty is true if the bin isn't written when the field is empty
+	OmitEmpty bool
+	// Required is true if absent bin is reported as error
+	Required bool
+	// Default is a Go literal which is decoded when the bin is absent
+	Default string
+}
+
 var funcMap = template.FuncMap{
 	"sub": func(i int) int {
 		return i - 1
@@ -14,81 +90,258 @@ var funcMap = template.FuncMap{
 	"inc": func(i int) int {
 		return i + 1
 	},
+	"camel":        parser.Camel,
+	"receiver":     receiver,
+	"unsupported":  unsupported,
+	"input":        input,
+	"decodeError":  decodeError,
+	"keyError":     keyError,
+	"lengthError":  lengthError,
+	"parseError":   parseError,
+	"missingError": missingError,
+	"restKeyError": restKeyError,
+	"unknownError": unknownError,
+	"knownKeys":    knownKeys,
+	"notEmpty":     notEmpty,
+	"assertion":    assertion,
+	"kind":         kind,
+	"enumError":    enumError,
+	"join":         strings.Join,
+	"quote":        strconv.Quote,
+}
+
+// unsupported stops generation of a query which doesn't describe any known type
+func unsupported(q node) (string, error) {
+	return "", fmt.Errorf("unsupported type %q", q.Type)
+}
+
+// receiver returns a receiver name for a type: Foo -> f
+func receiver(name string) string {
+	for _, r := range name {
+		return string(unicode.ToLower(r))
+	}
+
+	return "v"
 }
 
 const _map = `
-value_{{.Index}}, ok := {{if .IsTop}}data{{else}}raw_value_{{sub .Index}}{{end}}.(map[interface{}]interface{})
+value_{{.Index}}, ok := {{input .}}.(map[interface{}]interface{})
 if !ok {
-	return fmt.Errorf("...")
+	return ret, {{decodeError .}}
 }
 
 ret_{{.Index}} := make({{.Type}})
 for raw_key_{{.Index}}, raw_value_{{.Index}} := range value_{{.Index}} {
-	key_{{.Index}}, ok := raw_key_{{.Index}}.({{.KeyType}})
+	{{assertion . .KeyType .KeyKind (print "raw_key_" .Index) (print "key_" .Index)}}
 	if !ok {
-		return fmt.Errorf("key is not a string")
+		return ret, {{keyError .}}
 	}
-	{{if .Next.IsBuiltin}}
-		value_{{.Index}}, ok := raw_value_{{.Index}}.({{.Next.Type}})
-		if !ok {
-			return fmt.Errorf("...")
-		}
 
-		ret_{{.Index}}[key_{{.Index}}] = value_{{.Index}}
-	{{else}}
-		{{with .Next}}{{template "T" .}}{{end}}
-		ret_{{.Index}}[key_{{.Index}}] = ret_{{inc .Index}}
-	{{end}}
+	{{with .Next}}{{template "T" .}}{{end}}
+	ret_{{.Index}}[key_{{.Index}}] = ret_{{inc .Index}}
 }
 `
 
 const array = `
-value_{{.Index}}, ok := {{if .IsTop}}data{{else}}raw_value_{{sub .Index}}{{end}}.([]interface{})
+value_{{.Index}}, ok := {{input .}}.([]interface{})
 if !ok {
-	return fmt.Errorf("...")
+	return ret, {{decodeError .}}
 }
 
 ret_{{.Index}} := make({{.Type}}, 0, len(value_{{.Index}}))
-for _, raw_value_{{.Index}} := range value_{{.Index}} {
-	{{if .Next.IsBuiltin}}
-		element, ok := raw_value_{{.Index}}.({{.Next.Type}})
-		if !ok {
-			return fmt.Errorf("...")
+for i_{{.Index}}, raw_value_{{.Index}} := range value_{{.Index}} {
+	{{with .Next}}{{template "T" .}}{{end}}
+	ret_{{.Index}} = append(ret_{{.Index}}, ret_{{inc .Index}})
+}
+`
+
+// an array is filled in place, the list must have exactly the same length
+const fixedArray = `
+value_{{.Index}}, ok := {{input .}}.([]interface{})
+if !ok {
+	return ret, {{decodeError .}}
+}
+
+if len(value_{{.Index}}) != {{.Len}} {
+	return ret, {{lengthError .}}
+}
+
+var ret_{{.Index}} {{.Type}}
+for i_{{.Index}}, raw_value_{{.Index}} := range value_{{.Index}} {
+	{{with .Next}}{{template "T" .}}{{end}}
+	ret_{{.Index}}[i_{{.Index}}] = ret_{{inc .Index}}
+}
+`
+
+// a custom type decodes itself from any value or from a string by UnmarshalText
+const custom = `
+var ret_{{.Index}} {{.Type}}
+{{if .IsText}}
+	text_{{.Index}}, ok := {{input .}}.(string)
+	if !ok {
+		return ret, {{decodeError .}}
+	}
+
+	if err := ret_{{.Index}}.UnmarshalText([]byte(text_{{.Index}})); err != nil {
+		return ret, {{parseError . "err"}}
+	}
+{{else}}
+	if err := ret_{{.Index}}.UnmarshalBin({{input .}}); err != nil {
+		return ret, {{parseError . "err"}}
+	}
+{{end}}
+`
+
+// the client returns blobs as []byte, they are converted to the type without copying
+const blob = `
+value_{{.Index}}, ok := {{input .}}.([]byte)
+if !ok {
+	return ret, {{decodeError .}}
+}
+
+ret_{{.Index}} := {{.Type}}(value_{{.Index}})
+`
+
+// time is decoded from a string in RFC 3339 format or from unix seconds or nanoseconds,
+// 0 nanoseconds is decoded as zero time which is encoded as 0
+const _time = `
+{{if eq .TimeFormat "rfc3339"}}
+	text_{{.Index}}, ok := {{input .}}.(string)
+	if !ok {
+		return ret, {{decodeError .}}
+	}
+
+	ret_{{.Index}}, err := time.Parse(time.RFC3339, text_{{.Index}})
+	if err != nil {
+		return ret, {{parseError . "err"}}
+	}
+{{else}}
+	{{assertion . "int64" "" (input .) (print "number_" .Index)}}
+	if !ok {
+		return ret, {{decodeError .}}
+	}
+
+	{{if eq .TimeFormat "unix"}}
+		ret_{{.Index}} := time.Unix(number_{{.Index}}, 0)
+	{{else}}
+		var ret_{{.Index}} time.Time
+		if number_{{.Index}} != 0 {
+			ret_{{.Index}} = time.Unix(0, number_{{.Index}})
 		}
-	{{else if .Next.IsArray}}
-		{{with .Next}}{{template "TARR" .}}{{end}}
-	{{else if .Next.IsMap}}
-		{{with .Next}}{{template "TMAP" .}}{{end}}
-	{{else if .Next.IsStruct}}
-		{{with .Next}}{{template "TSTRUCT" .}}{{end}}
 	{{end}}
+{{end}}
+`
 
-	ret_{{.Index}} = append(ret_{{.Index}}, {{if .Next.IsBuiltin}}element{{else}}ret_{{inc .Index}}{{end}})
+// duration is decoded from integer nanoseconds
+const duration = `
+{{assertion . "int64" "" (input .) (print "number_" .Index)}}
+if !ok {
+	return ret, {{decodeError .}}
 }
+
+ret_{{.Index}} := time.Duration(number_{{.Index}})
 `
 
+// a value of enum must be one of declared constants
+// an empty interface holds any value including nil which fails the type assertion, so it's assigned as is
 const builtin = `
-ret_0, ok := data.({{.Type}})
-if !ok {
-	return fmt.Errorf("...")
-}
+{{if eq (kind .) "interface{}"}}
+	ret_{{.Index}} := {{if .Kind}}{{.Type}}({{input .}}){{else}}{{input .}}{{end}}
+{{else}}
+	{{assertion . .Type .Kind (input .) (print "ret_" .Index)}}
+	if !ok {
+		return ret, {{decodeError .}}
+	}
+{{end}}
+{{with .Enum}}
+	switch ret_{{$.Index}} {
+	case {{join . ", "}}:
+	default:
+		return ret, {{enumError $}}
+	}
+{{end}}
 `
 
+// every field is decoded in its own block, so the names of variables don't clash.
+// An absent field keeps zero value unless it's required or has a default value,
+// the default value is decoded as if the bin contains it.
+// The strict mode doesn't apply to omitempty fields, because their encoder omits empty values.
+// Keys which don't belong to any field are collected by the rest field, rejected or ignored,
+// a struct without fields only checks that the value is a map.
 const _struct = `
-	value_{{.Index}}, ok := {{if .IsTop}}data{{else}}raw_value_{{sub .Index}}{{end}}.(map[interface{}]interface{})
+{{if or .Fields .RejectUnknown}}
+	value_{{.Index}}, ok := {{input .}}.(map[interface{}]interface{})
 	if !ok {
-		return fmt.Errorf("data")
+		return ret, {{decodeError .}}
+	}
+{{else}}
+	if _, ok := {{input .}}.(map[interface{}]interface{}); !ok {
+		return ret, {{decodeError .}}
 	}
+{{end}}
 
-	ret_{{.Index}} := {{.Type}}{}
-	{{range $val := .Fields}}
-		{{$val.Name}}_value, ok := value_{{sub .Index}}["{{$val.Alias}}"].({{$val.Type}})
+ret_{{.Index}} := {{.Type}}{}
+{{range $val := .Fields}}{{if not $val.Rest}}
+{
+	raw_value_{{$.Index}}, ok := value_{{$.Index}}[{{quote $val.Alias}}]
+	{{if or $val.Required (and $.Strict (not $val.OmitEmpty))}}
 		if !ok {
-			return fmt.Errorf("{{$val.Name}}")
+			return ret, {{missingError $val}}
+		}
+	{{else if $val.Default}}
+		if !ok {
+			raw_value_{{$.Index}}, ok = {{kind $val}}({{$val.Default}}), true
 		}
-
-		ret_{{sub .Index}}.{{$val.Name}} = {{$val.Name}}_value
 	{{end}}
+	if ok {
+		{{template "T" $val}}
+		ret_{{$.Index}}.{{$val.Name}} = ret_{{$val.Index}}
+	}
+}
+{{end}}{{end}}
+{{if .RestField}}{{with .RestField}}
+	for raw_key_{{$.Index}}, raw_value_{{$.Index}} := range value_{{$.Index}} {
+		key_{{$.Index}}, ok := raw_key_{{$.Index}}.(string)
+		if !ok {
+			return ret, {{restKeyError $}}
+		}
+		{{with knownKeys $}}
+		switch key_{{$.Index}} {
+		case {{.}}:
+			continue
+		}
+		{{end}}
+		if ret_{{$.Index}}.{{.Name}} == nil {
+			ret_{{$.Index}}.{{.Name}} = make({{.Type}})
+		}
+		ret_{{$.Index}}.{{.Name}}[key_{{$.Index}}] = raw_value_{{$.Index}}
+	}
+{{end}}{{else if .RejectUnknown}}
+	var unknown_{{.Index}} []interface{}
+	for raw_key_{{.Index}} := range value_{{.Index}} {
+		{{with knownKeys .}}
+		if key_{{$.Index}}, ok := raw_key_{{$.Index}}.(string); ok {
+			switch key_{{$.Index}} {
+			case {{.}}:
+				continue
+			}
+		}
+		{{end}}
+		unknown_{{.Index}} = append(unknown_{{.Index}}, raw_key_{{.Index}})
+	}
+	if len(unknown_{{.Index}}) > 0 {
+		return ret, {{unknownError .}}
+	}
+{{end}}
+`
+
+// nil value is decoded as nil pointer
+const pointer = `
+var ret_{{.Index}} {{.Type}}
+if raw_value_{{.Index}} := {{input .}}; raw_value_{{.Index}} != nil {
+	{{with .Next}}{{template "T" .}}{{end}}
+	ret_{{.Index}} = &ret_{{inc .Index}}
+}
 `
 
 const main = `
@@ -96,30 +349,364 @@ const main = `
 	{{template "TMAP" .}}
 {{else if .IsArray}}
 	{{template "TARR" .}}
+{{else if .IsFixedArray}}
+	{{template "TFIXARR" .}}
 {{else if .IsBuiltin}}
 	{{template "TBUILTIN" .}}
+{{else if .IsBlob}}
+	{{template "TBLOB" .}}
+{{else if .IsCustom}}
+	{{template "TCUSTOM" .}}
+{{else if .IsTime}}
+	{{template "TTIME" .}}
+{{else if .IsDuration}}
+	{{template "TDURATION" .}}
 {{else if .IsStruct}}
 	{{template "TSTRUCT" .}}
+{{else if .IsPointer}}
+	{{template "TPTR" .}}
 {{else}}
-	panic("wrong type")
+	{{unsupported .}}
 {{end}}
 `
 
-// Genrate genrates a function body based on input query.
+const decoder = `
+// {{.Decoder}} decodes {{.Type}} from a value of the '{{.BinName}}' bin.
+func {{.Decoder}}(data interface{}) (ret {{.Type}}, err error) {
+	{{with .Root}}{{template "T" .}}{{end}}
+	return ret_0, nil
+}
+{{if .Method}}
+{{$recv := receiver .Name}}
+// UnmarshalBin decodes {{.Name}} from a value of the '{{.BinName}}' bin.
+func ({{$recv}} *{{.Name}}) UnmarshalBin(data interface{}) error {
+	ret, err := {{.Decoder}}(data)
+	if err != nil {
+		return err
+	}
+
+	*{{$recv}} = ret
+
+	return nil
+}
+{{end}}
+`
+
+const file = `// Code generated by molekula. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	{{range .Imports}}{{.Name}} "{{.Path}}"
+	{{end}}
+)
+{{range .Codecs}}
+	{{template "DECODER" .}}
+	{{template "ENCODER" .}}
+{{end}}
+{{range .Records}}
+	{{range .Bins}}{{if not .Shared}}
+		{{template "DECODER" .}}
+		{{template "ENCODER" .}}
+	{{end}}{{end}}
+	{{template "RECORD" .}}
+	{{template "KEY" .}}
+	{{if .Repo}}{{template "REPO" .}}{{end}}
+{{end}}
+{{if .Repo}}{{template "CLIENT"}}{{end}}
+`
+
+// the client accepts only a few types of keys, so values of named types are converted to their kinds
+const key = `
+{{with .Namespace}}
+// {{$.Name}}Namespace is a namespace of {{$.Name}} records.
+const {{$.Name}}Namespace = {{quote .}}
+{{end}}
+{{with .Set}}
+// {{$.Name}}Set is a set of {{$.Name}} records.
+const {{$.Name}}Set = {{quote .}}
+{{end}}
+{{with .KeyBin}}
+// {{$.Name}}NewKey returns a key of the record which stores v.
+func {{$.Name}}NewKey(v {{$.Name}}) (*aerospike.Key, error) {
+	return {{$.Name}}KeyFor(v.{{.Field}})
+}
+
+// {{$.Name}}KeyFor returns a key of {{$.Name}} record with the id.
+func {{$.Name}}KeyFor(id {{.Type}}) (*aerospike.Key, error) {
+	return aerospike.NewKey({{$.Name}}Namespace, {{if $.Set}}{{$.Name}}Set{{else}}""{{end}}, {{$.KeyKind}}(id))
+}
+{{end}}
+`
+
+// bins are decoded like fields of struct: an absent bin keeps zero value unless
+// the record is strict, the field is required or has a default value.
+// The strict mode doesn't apply to omitempty bins, because the encoder omits empty values.
+const _record = `
+// {{.Name}}FromBinMap decodes {{.Name}} from bins of a record.
+func {{.Name}}FromBinMap(bins aerospike.BinMap) (ret {{.Name}}, err error) {
+	{{range .Bins}}
+	{
+		data, ok := bins[{{quote .BinName}}]
+		{{if or .Required (and .Strict (not .OmitEmpty))}}
+			if !ok {
+				return ret, {{missingError .Root}}
+			}
+		{{else if .Default}}
+			if !ok {
+				data, ok = {{kind .Root}}({{.Default}}), true
+			}
+		{{end}}
+		if ok {
+			ret.{{.Field}}, err = {{.Decoder}}(data)
+			if err != nil {
+				return ret, err
+			}
+		}
+	}
+	{{end}}
+
+	return ret, nil
+}
+
+// {{.Name}}ToBinMap encodes {{.Name}} to bins of a record.
+{{- if .HasText}}
+// It panics if MarshalText of a value fails, because encoders don't return errors.
+{{- end}}
+func {{.Name}}ToBinMap(v {{.Name}}) aerospike.BinMap {
+	bins := make(aerospike.BinMap, {{len .Bins}})
+	{{range .Bins}}
+	{{if .OmitEmpty}}
+		if value := v.{{.Field}}; {{notEmpty .Root "value"}} {
+			bins[{{quote .BinName}}] = {{.Encoder}}(value)
+		}
+	{{else}}
+		bins[{{quote .BinName}}] = {{.Encoder}}(v.{{.Field}})
+	{{end}}
+	{{end}}
+
+	return bins
+}
+`
+
+var tmpl = template.Must(template.New("FILE").Funcs(funcMap).Parse(file))
+
+func init() {
+	template.Must(tmpl.New("DECODER").Parse(decoder))
+	template.Must(tmpl.New("RECORD").Parse(_record))
+	template.Must(tmpl.New("KEY").Parse(key))
+	template.Must(tmpl.New("TMAP").Parse(_map))
+	template.Must(tmpl.New("TARR").Parse(array))
+	template.Must(tmpl.New("TFIXARR").Parse(fixedArray))
+	template.Must(tmpl.New("TBUILTIN").Parse(builtin))
+	template.Must(tmpl.New("TBLOB").Parse(blob))
+	template.Must(tmpl.New("TCUSTOM").Parse(custom))
+	template.Must(tmpl.New("TTIME").Parse(_time))
+	template.Must(tmpl.New("TDURATION").Parse(duration))
+	template.Must(tmpl.New("TSTRUCT").Parse(_struct))
+	template.Must(tmpl.New("TPTR").Parse(pointer))
+	template.Must(tmpl.New("T").Parse(main))
+}
+
+// runtimePath is an import path of package with types which are used by the generated code
+const runtimePath = "github.com/nikgalushko/molekula"
+
+// codec is a Codec with a tree of nodes for templates
+type codec struct {
+	Codec
+	Root node
+}
+
+func newCodec(c Codec) codec {
+	if c.Type == "" {
+		c.Type = c.Name
+	}
+
+	if c.Func == "" {
+		c.Func = "Decode" + c.Name + parser.Camel(c.BinName)
+	}
+
+	return codec{
+		Codec: c,
+		Root: newNode(c.Query, context{
+			Bin:           c.BinName,
+			Path:          strings.ReplaceAll(c.BinName, "%", "%%"),
+			StrictNumeric: c.StrictNumeric,
+			Strict:        c.Strict,
+			RejectUnknown: c.RejectUnknown,
+		}),
+	}
+}
+
+// Decoder returns a name of decoder function
+func (c codec) Decoder() string {
+	return c.Func
+}
+
+// Encoder returns a name of encoder function
+func (c codec) Encoder() string {
+	return "Encode" + strings.TrimPrefix(c.Func, "Decode")
+}
+
+// bin is a Bin with a tree of nodes for templates
+type bin struct {
+	codec
+	// Shared is true if functions of the codec are generated for the type of the bin
+	Shared    bool
+	Field     string
+	OmitEmpty bool
+	Required  bool
+	Default   string
+}
+
+// record is a Record with trees of nodes of its bins
+type record struct {
+	Name      string
+	Bins      []bin
+	Namespace string
+	Set       string
+	Key       string
+	Repo      bool
+}
+
+// KeyBin returns a bin of key field or nil
+func (r record) KeyBin() *bin {
+	for i := range r.Bins {
+		if r.Bins[i].Field == r.Key {
+			return &r.Bins[i]
+		}
+	}
+
+	return nil
+}
+
+// HasText returns true if any bin is encoded with MarshalText which may panic
+func (r record) HasText() bool {
+	for _, b := range r.Bins {
+		if b.Root.HasText() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// KeyKind returns a type of value which is passed to the client as a key, named types are converted to it.
+// Integer keys are stored as int64, so integers which fit are widened to it.
+func (r record) KeyKind() string {
+	root := r.KeyBin().Root
+	switch k := kind(root); {
+	case root.IsBlob:
+		return "[]byte"
+	case k == "uint", k == "uint64", k == "uintptr":
+		return k
+	case numbers[k].convert == "Int" || numbers[k].convert == "Uint":
+		return "int64"
+	default:
+		return k
+	}
+}
+
+// Generate generates a formatted source of file with decoders and encoders of all codecs.
 // It's naive implementation. It's assumed that the parser.Object is valid and fully complies with the specification.
-func Generate(q query.Query) (string, error) {
-	tmpl := template.Must(template.New("TMAP").Funcs(funcMap).Parse(_map))
-	tmpl = template.Must(tmpl.New("TARR").Parse(array))
-	tmpl = template.Must(tmpl.New("TBUILTIN").Parse(builtin))
-	tmpl = template.Must(tmpl.New("TSTRUCT").Parse(_struct))
-	tmpl = template.Must(tmpl.New("T").Parse(main))
+func Generate(f File) ([]byte, error) {
+	codecs := make([]codec, 0, len(f.Codecs))
+	for _, c := range f.Codecs {
+		codecs = append(codecs, newCodec(c))
+	}
+
+	paths := append([]parser.Import{{Path: runtimePath}}, f.Imports...)
+
+	decoders := make(map[string]string, len(codecs))
+	for _, c := range codecs {
+		decoders[c.Type] = c.Decoder()
+	}
+
+	// repo is true if the MolekulaClient interface is used by any repository of the file
+	repo := false
+	records := make([]record, 0, len(f.Records))
+	for _, r := range f.Records {
+		bins := make([]bin, 0, len(r.Bins))
+		for _, b := range r.Bins {
+			c := newCodec(b.Codec)
+			decoder, shared := decoders[c.Type]
+			if shared {
+				c.Func = decoder
+			}
+
+			bins = append(bins, bin{
+				codec:     c,
+				Shared:    shared,
+				Field:     b.Field,
+				OmitEmpty: b.OmitEmpty,
+				Required:  b.Required,
+				Default:   b.Default,
+			})
+		}
+		records = append(records, record{Name: r.Name, Bins: bins, Namespace: r.Namespace, Set: r.Set, Key: r.Key, Repo: r.Repo})
+		paths = append(paths, parser.Import{Path: f.Client})
+
+		if r.Repo {
+			repo = true
+			paths = append(paths, parser.Import{Path: "context"})
+		}
+	}
 
 	ret := bytes.NewBuffer(nil)
 
-	err := tmpl.Execute(ret, q)
+	err := tmpl.Execute(ret, struct {
+		Package string
+		Imports []parser.Import
+		Codecs  []codec
+		Records []record
+		Repo    bool
+	}{
+		Package: f.Package,
+		Imports: imports(paths),
+		Codecs:  codecs,
+		Records: records,
+		Repo:    repo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return formatSource(ret.Bytes())
+}
+
+// formatSource formats the generated code and removes the import of runtime package if it isn't used,
+// e.g. codecs of empty interfaces don't report errors.
+func formatSource(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := goparser.ParseFile(fset, "", src, goparser.ParseComments)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+
+	if !astutil.UsesImport(f, runtimePath) {
+		astutil.DeleteImport(fset, f, runtimePath)
+	}
+
+	ret := bytes.NewBuffer(nil)
+	if err := format.Node(ret, fset, f); err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+
+	return ret.Bytes(), nil
+}
+
+// imports returns a list of unique imports sorted by paths
+func imports(paths []parser.Import) []parser.Import {
+	set := make(map[parser.Import]struct{})
+	for _, path := range paths {
+		set[path] = struct{}{}
+	}
+
+	ret := make([]parser.Import, 0, len(set))
+	for path := range set {
+		ret = append(ret, path)
 	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Path < ret[j].Path })
 
-	return ret.String(), nil
+	return ret
 }
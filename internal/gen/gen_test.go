@@ -1,10 +1,16 @@
 package gen
 
 import (
+	stdcontext "context"
+	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/nikgalushko/molekula"
+	"github.com/nikgalushko/molekula/internal/parser"
 	"github.com/nikgalushko/molekula/internal/query"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,10 +26,7 @@ func TestGenerate_Builtin(t *testing.T) {
 		Type:      "uint",
 	}
 
-	s, err := Generate(q)
-	assert.NoError(t, err)
-
-	f, err := buildCallableFunction(buildSettings{src: s, typeOfResult: "uint"})
+	f, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "uint"})
 	assert.NoError(t, err)
 
 	ret, err := f.(func(interface{}) (uint, error))(uint(16))
@@ -39,10 +42,7 @@ func TestGenerate_SimpleArray(t *testing.T) {
 		Next:    &query.Query{IsBuiltin: true, Index: 1, Type: "int"},
 	}
 
-	s, err := Generate(q)
-	assert.NoError(t, err)
-
-	f, err := buildCallableFunction(buildSettings{src: s, typeOfResult: "[]int"})
+	f, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "[]int"})
 	assert.NoError(t, err)
 
 	ret, err := f.(func(interface{}) ([]int, error))([]interface{}{1, 2, 3})
@@ -63,10 +63,7 @@ func TestGenerate_SimpleNestedArray(t *testing.T) {
 		},
 	}
 
-	s, err := Generate(q)
-	assert.NoError(t, err)
-
-	f, err := buildCallableFunction(buildSettings{src: s, typeOfResult: "[][]string"})
+	f, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "[][]string"})
 	assert.NoError(t, err)
 
 	ret, err := f.(func(interface{}) ([][]string, error))([]interface{}{
@@ -86,10 +83,7 @@ func TestGenerate_SimpleMap(t *testing.T) {
 		Next:    &query.Query{IsBuiltin: true, Index: 1, Type: "string"},
 	}
 
-	s, err := Generate(q)
-	assert.NoError(t, err)
-
-	f, err := buildCallableFunction(buildSettings{src: s, typeOfResult: "map[int]string"})
+	f, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "map[int]string"})
 	assert.NoError(t, err)
 
 	ret, err := f.(func(interface{}) (map[int]string, error))(map[interface{}]interface{}{
@@ -115,10 +109,7 @@ func TestGenerate_SimpleNestedMap(t *testing.T) {
 		},
 	}
 
-	s, err := Generate(q)
-	assert.NoError(t, err)
-
-	f, err := buildCallableFunction(buildSettings{src: s, typeOfResult: "map[int]map[string]float64"})
+	f, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "map[int]map[string]float64"})
 	assert.NoError(t, err)
 
 	ret, err := f.(func(interface{}) (map[int]map[string]float64, error))(map[interface{}]interface{}{
@@ -143,10 +134,7 @@ func TestGenerate_MapOfArray(t *testing.T) {
 		},
 	}
 
-	s, err := Generate(q)
-	assert.NoError(t, err)
-
-	f, err := buildCallableFunction(buildSettings{src: s, typeOfResult: "map[int][]int64"})
+	f, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "map[int][]int64"})
 	assert.NoError(t, err)
 
 	ret, err := f.(func(interface{}) (map[int][]int64, error))(map[interface{}]interface{}{
@@ -163,16 +151,13 @@ func TestGenerate_Struct(t *testing.T) {
 		IsStruct: true,
 		Type:     "custom.Foo",
 		Fields: []query.Query{
-			{Name: "Gender", Alias: "gender", Index: 1, Type: "string"},
-			{Name: "ID", Alias: "id", Index: 1, Type: "int64"},
+			{Name: "Gender", Alias: "gender", Index: 1, Type: "string", IsBuiltin: true},
+			{Name: "ID", Alias: "id", Index: 1, Type: "int64", IsBuiltin: true},
 		},
 	}
 
-	s, err := Generate(q)
-	require.NoError(t, err)
-
 	f, err := buildCallableFunction(buildSettings{
-		src:                   s,
+		query:                 q,
 		typeOfResult:          "custom.Foo",
 		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
 	})
@@ -197,17 +182,14 @@ func TestGenerate_MapOfStruct(t *testing.T) {
 			Type:     "custom.Foo",
 			Index:    1,
 			Fields: []query.Query{
-				{Name: "Gender", Alias: "gender", Index: 2, Type: "string"},
-				{Name: "ID", Alias: "id", Index: 2, Type: "int64"},
+				{Name: "Gender", Alias: "gender", Index: 2, Type: "string", IsBuiltin: true},
+				{Name: "ID", Alias: "id", Index: 2, Type: "int64", IsBuiltin: true},
 			},
 		},
 	}
 
-	s, err := Generate(q)
-	require.NoError(t, err)
-
 	f, err := buildCallableFunction(buildSettings{
-		src:                   s,
+		query:                 q,
 		typeOfResult:          "map[string]custom.Foo",
 		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
 	})
@@ -241,17 +223,14 @@ func TestGenerate_ArrayOfStruct(t *testing.T) {
 			Type:     "custom.Foo",
 			Index:    1,
 			Fields: []query.Query{
-				{Name: "Gender", Alias: "gender", Index: 2, Type: "string"},
-				{Name: "ID", Alias: "id", Index: 2, Type: "int64"},
+				{Name: "Gender", Alias: "gender", Index: 2, Type: "string", IsBuiltin: true},
+				{Name: "ID", Alias: "id", Index: 2, Type: "int64", IsBuiltin: true},
 			},
 		},
 	}
 
-	s, err := Generate(q)
-	require.NoError(t, err)
-
 	f, err := buildCallableFunction(buildSettings{
-		src:                   s,
+		query:                 q,
 		typeOfResult:          "[]custom.Foo",
 		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
 	})
@@ -274,56 +253,1441 @@ func TestGenerate_ArrayOfStruct(t *testing.T) {
 	}, ret)
 }
 
-type Foo struct {
-	Gender string
-	ID     int64
+func TestGenerate_Method(t *testing.T) {
+	src, err := Generate(File{
+		Package: "foo",
+		Codecs: []Codec{{
+			Name:    "Weights",
+			BinName: "weights_v2",
+			Method:  true,
+			Query: query.Query{
+				IsTop:   true,
+				IsArray: true,
+				Type:    "[]float64",
+				Next:    &query.Query{IsBuiltin: true, Index: 1, Type: "float64"},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "func DecodeWeightsWeightsV2(data interface{}) (ret Weights, err error)")
+	assert.NotContains(t, string(src), "panics")
+
+	i := newInterpreter()
+
+	_, err = i.Eval(string(src) + `
+		type Weights []float64
+
+		func call(data interface{}) ([]float64, error) {
+			var w Weights
+			err := w.UnmarshalBin(data)
+			return []float64(w), err
+		}
+	`)
+	require.NoError(t, err)
+
+	v, err := i.Eval("foo.call")
+	require.NoError(t, err)
+
+	ret, err := v.Interface().(func(interface{}) ([]float64, error))([]interface{}{0.5, 1.5})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 1.5}, ret)
+
+	_, err = v.Interface().(func(interface{}) ([]float64, error))("wrong")
+	assert.Error(t, err)
 }
 
-type buildSettings struct {
-	src                   string
-	typeOfResult          string
-	specialTypeDefinition reflect.Value
+func TestGenerate_RuntimeImport(t *testing.T) {
+	// a file without codecs and codecs of empty interfaces don't use the runtime package
+	for _, codecs := range [][]Codec{nil, {{
+		Name:    "Any",
+		BinName: "any",
+		Query:   query.Query{IsTop: true, IsBuiltin: true, Type: "interface{}"},
+	}}} {
+		src, err := Generate(File{Package: "foo", Codecs: codecs})
+		require.NoError(t, err)
+		assert.NotContains(t, string(src), runtimePath)
+
+		_, err = newInterpreter().Eval(string(src) + "\ntype Any interface{}\n")
+		require.NoError(t, err)
+	}
 }
 
-func buildCallableFunction(s buildSettings) (interface{}, error) {
-	pkgTemplate := `
-		package foo
+func TestGenerate_ImportNames(t *testing.T) {
+	src, err := Generate(File{
+		Package: "foo",
+		Imports: []parser.Import{{Path: "example.com/legacy/models", Name: "models2"}, {Path: "example.com/models"}},
+		Codecs: []Codec{{
+			Name:    "Users",
+			BinName: "users",
+			Query:   query.Query{IsTop: true, IsBuiltin: true, Type: "interface{}"},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "models2 \"example.com/legacy/models\"\n\t\"example.com/models\"\n")
+}
+
+func TestGenerate_EncodeMapOfArray(t *testing.T) {
+	q := query.Query{
+		IsTop:   true,
+		IsMap:   true,
+		Type:    "map[int][]int64",
+		KeyType: "int",
+		Next: &query.Query{
+			Index:   1,
+			IsArray: true,
+			Type:    "[]int64",
+			Next:    &query.Query{IsBuiltin: true, Index: 2, Type: "int64"},
+		},
+	}
 
-		import (
-			"fmt"
-			"custom"
-		)
+	f, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "map[int][]int64", function: "EncodeTBin"})
+	require.NoError(t, err)
 
-		var ret %s
+	value := map[int][]int64{1: {0, 1}, 2: {1, 0}}
 
-		func wrapper(data interface{}) (%s, error) {
-			err := pasrse(data)
-			return ret, err
-		}
+	encoded := f.(func(map[int][]int64) interface{})(value)
+	assert.Equal(t, map[interface{}]interface{}{
+		1: []interface{}{int64(0), int64(1)},
+		2: []interface{}{int64(1), int64(0)},
+	}, encoded)
 
-		func pasrse(data interface{}) error {
-			%s
-			ret = ret_0
-			return nil
-		}
-	`
-	i := interp.New(interp.Options{})
-	i.Use(stdlib.Symbols)
+	decode, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "map[int][]int64"})
+	require.NoError(t, err)
 
-	custom := make(map[string]map[string]reflect.Value)
-	custom["custom"] = make(map[string]reflect.Value)
-	custom["custom"]["Foo"] = s.specialTypeDefinition
+	ret, err := decode.(func(interface{}) (map[int][]int64, error))(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, value, ret)
+}
 
-	i.Use(custom)
+func TestGenerate_EmptyInterface(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Any",
+		Fields: []query.Query{
+			{Name: "Value", Alias: "value", Index: 1, Type: "interface{}", IsBuiltin: true},
+			{
+				Name: "Values", Alias: "values", Index: 1, Type: "[]interface{}", IsArray: true,
+				Next: &query.Query{Index: 2, Type: "interface{}", IsBuiltin: true},
+			},
+		},
+	}
 
-	//fmt.Println(fmt.Sprintf(pkgTemplate, s.typeOfResult, s.typeOfResult, s.src))
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Any",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
 
-	_, err := i.Eval(fmt.Sprintf(pkgTemplate, s.typeOfResult, s.typeOfResult, s.src))
-	if err != nil {
-		return nil, err
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Any",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	// nil values are kept, they aren't decode errors
+	ret, err := decode.(func(interface{}) (Any, error))(encode.(func(Any) interface{})(Any{}))
+	require.NoError(t, err)
+	assert.Equal(t, Any{Values: []interface{}{}}, ret)
+
+	value := Any{Value: "v", Values: []interface{}{nil, int64(1)}}
+	ret, err = decode.(func(interface{}) (Any, error))(encode.(func(Any) interface{})(value))
+	require.NoError(t, err)
+	assert.Equal(t, value, ret)
+}
+
+func TestGenerate_EmptyStruct(t *testing.T) {
+	q := query.Query{
+		IsTop:   true,
+		IsArray: true,
+		Type:    "[]struct{}",
+		Next:    &query.Query{Index: 1, IsStruct: true, Type: "struct{}"},
+	}
+
+	encode, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "[]struct{}", function: "EncodeTBin"})
+	require.NoError(t, err)
+
+	decode, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "[]struct{}"})
+	require.NoError(t, err)
+
+	value := []struct{}{{}, {}}
+	encoded := encode.(func([]struct{}) interface{})(value)
+	assert.Equal(t, []interface{}{map[interface{}]interface{}{}, map[interface{}]interface{}{}}, encoded)
+
+	ret, err := decode.(func(interface{}) ([]struct{}, error))(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, value, ret)
+
+	_, err = decode.(func(interface{}) ([]struct{}, error))([]interface{}{"wrong"})
+	assert.Error(t, err)
+}
+
+func TestGenerate_EncodeArrayOfStruct(t *testing.T) {
+	q := query.Query{
+		IsTop:   true,
+		IsArray: true,
+		Type:    "[]custom.Foo",
+		Next: &query.Query{
+			IsStruct: true,
+			Type:     "custom.Foo",
+			Index:    1,
+			Fields: []query.Query{
+				{Name: "Gender", Alias: "gender", Index: 2, Type: "string", IsBuiltin: true},
+				{Name: "ID", Alias: "id", Index: 2, Type: "int64", IsBuiltin: true},
+			},
+		},
+	}
+
+	f, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "[]custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	value := []Foo{{Gender: "m", ID: 999}, {Gender: "w", ID: 888}}
+
+	encoded := f.(func([]Foo) interface{})(value)
+	assert.Equal(t, []interface{}{
+		map[interface{}]interface{}{"gender": "m", "id": int64(999)},
+		map[interface{}]interface{}{"gender": "w", "id": int64(888)},
+	}, encoded)
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "[]custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	ret, err := decode.(func(interface{}) ([]Foo, error))(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, value, ret)
+}
+
+func TestGenerate_StructWithNestedFields(t *testing.T) {
+	foo := query.Query{
+		IsStruct: true,
+		Type:     "custom.Foo",
+		Index:    3,
+		Fields: []query.Query{
+			{Name: "Gender", Alias: "gender", Index: 4, Type: "string", IsBuiltin: true},
+			{Name: "ID", Alias: "id", Index: 4, Type: "int64", IsBuiltin: true},
+		},
+	}
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Bar",
+		Fields: []query.Query{
+			{
+				Name: "Tags", Alias: "tags", Index: 1, Type: "[]string", IsArray: true,
+				Next: &query.Query{Index: 2, Type: "string", IsBuiltin: true},
+			},
+			{
+				Name: "Users", Alias: "users", Index: 1, Type: "map[string][]custom.Foo", IsMap: true, KeyType: "string",
+				Next: &query.Query{Index: 2, Type: "[]custom.Foo", IsArray: true, Next: &foo},
+			},
+		},
+	}
+
+	f, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Bar",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	data := map[interface{}]interface{}{
+		"tags": []interface{}{"a", "b"},
+		"users": map[interface{}]interface{}{
+			"eu": []interface{}{
+				map[interface{}]interface{}{"gender": "m", "id": int64(1)},
+				map[interface{}]interface{}{"gender": "w", "id": int64(2)},
+			},
+		},
+	}
+	value := Bar{
+		Tags:  []string{"a", "b"},
+		Users: map[string][]Foo{"eu": {{Gender: "m", ID: 1}, {Gender: "w", ID: 2}}},
+	}
+
+	ret, err := f.(func(interface{}) (Bar, error))(data)
+	require.NoError(t, err)
+	assert.Equal(t, value, ret)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Bar",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, data, encode.(func(Bar) interface{})(value))
+}
+
+func TestGenerate_DecodeError(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Bar",
+		Fields: []query.Query{
+			{
+				Name: "Tags", Alias: "tags", Index: 1, Type: "[]string", IsArray: true,
+				Next: &query.Query{Index: 2, Type: "string", IsBuiltin: true},
+			},
+			{
+				Name: "Users", Alias: "users", Index: 1, Type: "map[string][]custom.Foo", IsMap: true, KeyType: "string",
+				Next: &query.Query{
+					Index: 2, Type: "[]custom.Foo", IsArray: true,
+					Next: &query.Query{
+						IsStruct: true,
+						Type:     "custom.Foo",
+						Index:    3,
+						Fields: []query.Query{
+							{Name: "Gender", Alias: "gender", Index: 4, Type: "string", IsBuiltin: true},
+							{Name: "ID", Alias: "id", Index: 4, Type: "int64", IsBuiltin: true},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Bar",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		data interface{}
+		err  *molekula.DecodeError
+	}{
+		"wrong bin type": {
+			data: "bar",
+			err:  &molekula.DecodeError{Bin: "bin", Path: "bin", Expected: "custom.Bar", Got: "string"},
+		},
+		"wrong element of slice": {
+			data: map[interface{}]interface{}{
+				"tags": []interface{}{"a", 1},
+			},
+			err: &molekula.DecodeError{Bin: "bin", Path: "bin.Tags[1]", Expected: "string", Got: "int"},
+		},
+		"wrong key of map": {
+			data: map[interface{}]interface{}{
+				"tags":  []interface{}{},
+				"users": map[interface{}]interface{}{1: []interface{}{}},
+			},
+			err: &molekula.DecodeError{Bin: "bin", Path: "bin.Users[1]", Expected: "string", Got: "int", Key: true},
+		},
+		"wrong field of nested struct": {
+			data: map[interface{}]interface{}{
+				"tags": []interface{}{},
+				"users": map[interface{}]interface{}{
+					"eu": []interface{}{
+						map[interface{}]interface{}{"gender": "m", "id": int64(1)},
+						map[interface{}]interface{}{"gender": "w", "id": "2"},
+					},
+				},
+			},
+			err: &molekula.DecodeError{Bin: "bin", Path: `bin.Users["eu"][1].ID`, Expected: "int64", Got: "string"},
+		},
+		"nil field": {
+			data: map[interface{}]interface{}{"tags": nil},
+			err:  &molekula.DecodeError{Bin: "bin", Path: "bin.Tags", Expected: "[]string", Got: "<nil>"},
+		},
+	}
+
+	for title, tt := range tests {
+		_, err := f.(func(interface{}) (Bar, error))(tt.data)
+		assert.Equal(t, tt.err, err, title)
+	}
+}
+
+func TestGenerate_FixedArray(t *testing.T) {
+	q := query.Query{
+		IsTop:        true,
+		IsFixedArray: true,
+		Type:         "[3]float64",
+		Len:          3,
+		Next:         &query.Query{IsBuiltin: true, Index: 1, Type: "float64"},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "[3]float64"})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "[3]float64", function: "EncodeTBin"})
+	require.NoError(t, err)
+
+	ret, err := decode.(func(interface{}) ([3]float64, error))([]interface{}{0.1, 0.2, 0.3})
+	require.NoError(t, err)
+	assert.Equal(t, [3]float64{0.1, 0.2, 0.3}, ret)
+
+	_, err = decode.(func(interface{}) ([3]float64, error))([]interface{}{0.1, 0.2})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin", Expected: "[3]float64", Got: "[]interface{} of length 2"}, err)
+
+	_, err = decode.(func(interface{}) ([3]float64, error))([]interface{}{0.1, "0.2", 0.3})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin[1]", Expected: "float64", Got: "string"}, err)
+
+	assert.Equal(t, []interface{}{0.1, 0.2, 0.3}, encode.(func([3]float64) interface{})([3]float64{0.1, 0.2, 0.3}))
+}
+
+func TestGenerate_Blob(t *testing.T) {
+	q := query.Query{
+		IsTop:   true,
+		IsMap:   true,
+		Type:    "map[string]custom.Hash",
+		KeyType: "string",
+		Next:    &query.Query{IsBlob: true, Index: 1, Type: "custom.Hash"},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "map[string]custom.Hash",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "map[string]custom.Hash",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	ret, err := decode.(func(interface{}) (map[string]Hash, error))(map[interface{}]interface{}{"md5": []byte{0xca, 0xfe}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Hash{"md5": {0xca, 0xfe}}, ret)
+
+	_, err = decode.(func(interface{}) (map[string]Hash, error))(map[interface{}]interface{}{"md5": []interface{}{0xca, 0xfe}})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: `bin["md5"]`, Expected: "custom.Hash", Got: "[]interface {}"}, err)
+
+	assert.Equal(t,
+		map[interface{}]interface{}{"md5": []byte{0xca, 0xfe}},
+		encode.(func(map[string]Hash) interface{})(map[string]Hash{"md5": {0xca, 0xfe}}),
+	)
+}
+
+func TestGenerate_Time(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Event",
+		Fields: []query.Query{
+			{Name: "At", Alias: "at", Index: 1, Type: "time.Time", IsTime: true, TimeFormat: "unixnano"},
+			{Name: "Created", Alias: "created", Index: 1, Type: "time.Time", IsTime: true, TimeFormat: "unix", OmitEmpty: true},
+			{Name: "Updated", Alias: "updated", Index: 1, Type: "time.Time", IsTime: true, TimeFormat: "rfc3339"},
+			{Name: "TTL", Alias: "ttl", Index: 1, Type: "time.Duration", IsDuration: true},
+		},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Event",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Event",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	at := time.Date(2021, 3, 8, 10, 30, 0, 500, time.UTC)
+	data := map[interface{}]interface{}{
+		"at":      at.UnixNano(),
+		"created": at.Unix(),
+		"updated": "2021-03-08T10:30:00.0000005Z",
+		"ttl":     int64(time.Minute),
+	}
+
+	ret, err := decode.(func(interface{}) (Event, error))(data)
+	require.NoError(t, err)
+	assert.True(t, at.Equal(ret.At), ret.At)
+	assert.True(t, at.Truncate(time.Second).Equal(ret.Created), ret.Created)
+	assert.True(t, at.Equal(ret.Updated), ret.Updated)
+	assert.Equal(t, time.Minute, ret.TTL)
+
+	assert.Equal(t, data, encode.(func(Event) interface{})(Event{At: at, Created: at, Updated: at, TTL: time.Minute}))
+	assert.NotContains(t, encode.(func(Event) interface{})(Event{}), "created")
+
+	// zero time is out of range of unix nanoseconds, it's encoded as 0 and decoded back
+	zero := encode.(func(Event) interface{})(Event{})
+	assert.Equal(t, int64(0), zero.(map[interface{}]interface{})["at"])
+
+	ret, err = decode.(func(interface{}) (Event, error))(zero)
+	require.NoError(t, err)
+	assert.True(t, ret.At.IsZero(), ret.At)
+	assert.True(t, ret.Created.IsZero(), ret.Created)
+	assert.True(t, ret.Updated.IsZero(), ret.Updated)
+
+	_, err = decode.(func(interface{}) (Event, error))(map[interface{}]interface{}{"updated": "yesterday"})
+	require.Error(t, err)
+	assert.Equal(t, "bin.Updated", err.(*molekula.DecodeError).Path)
+	assert.Equal(t, "string", err.(*molekula.DecodeError).Got)
+	assert.Error(t, errors.Unwrap(err))
+
+	_, err = decode.(func(interface{}) (Event, error))(map[interface{}]interface{}{"ttl": "1m"})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.TTL", Expected: "time.Duration", Got: "string"}, err)
+}
+
+func TestGenerate_Custom(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Payment",
+		Fields: []query.Query{
+			{
+				Name: "Amounts", Alias: "amounts", Index: 1, Type: "[]custom.Money", IsArray: true,
+				Next: &query.Query{Index: 2, Type: "custom.Money", IsCustom: true},
+			},
+			{
+				Name: "IP", Alias: "ip", Index: 1, Type: "*net.IP", IsPointer: true,
+				Next: &query.Query{Index: 2, Type: "net.IP", IsCustom: true, IsText: true},
+			},
+		},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Payment",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Payment",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	ip := net.IPv4(10, 0, 0, 1)
+	data := map[interface{}]interface{}{"amounts": []interface{}{"1.05", "20.00"}, "ip": "10.0.0.1"}
+
+	ret, err := decode.(func(interface{}) (Payment, error))(data)
+	require.NoError(t, err)
+	assert.Equal(t, []Money{105, 2000}, ret.Amounts)
+	require.NotNil(t, ret.IP)
+	assert.True(t, ip.Equal(*ret.IP))
+
+	assert.Equal(t, data, encode.(func(Payment) interface{})(Payment{Amounts: []Money{105, 2000}, IP: &ip}))
+
+	_, err = decode.(func(interface{}) (Payment, error))(map[interface{}]interface{}{"amounts": []interface{}{"1.05", 2}})
+	require.Error(t, err)
+	assert.Equal(t, "bin.Amounts[1]", err.(*molekula.DecodeError).Path)
+	assert.EqualError(t, errors.Unwrap(err), "money: expected string, got int")
+
+	_, err = decode.(func(interface{}) (Payment, error))(map[interface{}]interface{}{"ip": "localhost"})
+	require.Error(t, err)
+	assert.Equal(t, "bin.IP", err.(*molekula.DecodeError).Path)
+	assert.Error(t, errors.Unwrap(err))
+
+	_, err = decode.(func(interface{}) (Payment, error))(map[interface{}]interface{}{"ip": []byte{10, 0, 0, 1}})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.IP", Expected: "net.IP", Got: "[]uint8"}, err)
+
+	// encoders of text values panic on errors of MarshalText, it's documented
+	src, err := Generate(File{Package: "foo", Codecs: []Codec{{Name: "Payment", BinName: "payment", Method: true, Query: q}}})
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "// EncodePaymentPayment encodes Payment to a value of the 'payment' bin.\n"+
+		"// It panics if MarshalText of a value fails, because encoders don't return errors.\n")
+	assert.Contains(t, string(src), "// MarshalBin encodes Payment to a value of the 'payment' bin.\n// It panics if MarshalText of a value fails.\n")
+}
+
+func TestGenerate_Named(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Member",
+		Fields: []query.Query{
+			{Name: "Level", Alias: "level", Index: 1, Type: "custom.Level", Kind: "int", IsBuiltin: true, Default: "1"},
+			{
+				Name: "Status", Alias: "status", Index: 1, Type: "custom.Status", Kind: "string", IsBuiltin: true, OmitEmpty: true,
+				Enum: []string{`"active"`, `"blocked"`},
+			},
+			{
+				Name: "Scores", Alias: "scores", Index: 1, Type: "map[custom.Level]float64", IsMap: true,
+				KeyType: "custom.Level", KeyKind: "int",
+				Next: &query.Query{Index: 2, Type: "float64", IsBuiltin: true},
+			},
+		},
+	}
+
+	for _, strict := range []bool{false, true} {
+		decode, err := buildCallableFunction(buildSettings{
+			query:                 q,
+			typeOfResult:          "custom.Member",
+			specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+			strictNumeric:         strict,
+		})
+		require.NoError(t, err)
+
+		ret, err := decode.(func(interface{}) (Member, error))(map[interface{}]interface{}{
+			"status": "blocked",
+			"scores": map[interface{}]interface{}{2: 0.5},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Member{Level: 1, Status: "blocked", Scores: map[Level]float64{2: 0.5}}, ret)
+
+		_, err = decode.(func(interface{}) (Member, error))(map[interface{}]interface{}{"status": "deleted"})
+		assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.Status", Expected: "custom.Status", Got: "string", Value: `"deleted"`}, err)
+	}
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Member",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		map[interface{}]interface{}{"level": 3, "scores": map[interface{}]interface{}{1: 0.5}},
+		encode.(func(Member) interface{})(Member{Level: 3, Scores: map[Level]float64{1: 0.5}}),
+	)
+}
+
+func TestGenerate_Record(t *testing.T) {
+	bins := []Bin{
+		{
+			Codec: Codec{
+				Name: "T", BinName: "lvl", Type: "custom.Level",
+				Query: query.Query{IsTop: true, Type: "custom.Level", Kind: "int", IsBuiltin: true},
+			},
+			Field:   "Level",
+			Default: "1",
+		},
+		{
+			Codec: Codec{
+				Name: "T", BinName: "status", Type: "custom.Status",
+				Query: query.Query{IsTop: true, Type: "custom.Status", Kind: "string", IsBuiltin: true, Enum: []string{`"active"`, `"blocked"`}},
+			},
+			Field:    "Status",
+			Required: true,
+		},
+		{
+			Codec: Codec{
+				Name: "T", BinName: "scores", Type: "map[custom.Level]float64", Strict: true,
+				Query: query.Query{
+					IsTop: true, Type: "map[custom.Level]float64", IsMap: true, KeyType: "custom.Level", KeyKind: "int",
+					Next: &query.Query{Index: 1, Type: "float64", IsBuiltin: true},
+				},
+			},
+			Field:     "Scores",
+			OmitEmpty: true,
+		},
+	}
+
+	settings := buildSettings{
+		typeOfResult:          "custom.Member",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		records:               []Record{{Name: "T", Bins: bins}},
+		function:              "TFromBinMap",
+	}
+
+	decode, err := buildCallableFunction(settings)
+	require.NoError(t, err)
+
+	ret, err := decode.(func(BinMap) (Member, error))(BinMap{
+		"status": "active",
+		"scores": map[interface{}]interface{}{2: 0.5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Member{Level: 1, Status: "active", Scores: map[Level]float64{2: 0.5}}, ret)
+
+	_, err = decode.(func(BinMap) (Member, error))(BinMap{"lvl": 2})
+	assert.Equal(t, &molekula.DecodeError{Bin: "status", Path: "status", Expected: "custom.Status", Got: "<nil>", Missing: true}, err)
+
+	_, err = decode.(func(BinMap) (Member, error))(BinMap{"status": "deleted"})
+	assert.Equal(t, &molekula.DecodeError{Bin: "status", Path: "status", Expected: "custom.Status", Got: "string", Value: `"deleted"`}, err)
+
+	settings.function = "TToBinMap"
+	encode, err := buildCallableFunction(settings)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		BinMap{"lvl": 3, "status": ""},
+		encode.(func(Member) BinMap)(Member{Level: 3}),
+	)
+
+	// the strict mode doesn't apply to omitempty bins which the encoder omits
+	member := Member{Level: 3, Status: "active"}
+	ret, err = decode.(func(BinMap) (Member, error))(encode.(func(Member) BinMap)(member))
+	require.NoError(t, err)
+	assert.Equal(t, member, ret)
+
+	settings.records[0].Namespace, settings.records[0].Set, settings.records[0].Key = "prod", "members", "Level"
+	settings.function = "TNewKey"
+	newKey, err := buildCallableFunction(settings)
+	require.NoError(t, err)
+
+	key, err := newKey.(func(Member) (*Key, error))(Member{Level: 3})
+	require.NoError(t, err)
+	assert.Equal(t, &Key{Namespace: "prod", Set: "members", Value: int64(3)}, key)
+}
+
+func TestGenerate_RecordSharedCodec(t *testing.T) {
+	level := query.Query{IsTop: true, Type: "custom.Level", Kind: "int", IsBuiltin: true}
+
+	settings := buildSettings{
+		typeOfResult:          "custom.Member",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		codecs:                []Codec{{Name: "Level", Type: "custom.Level", BinName: "level", StrictNumeric: true, Query: level}},
+		records: []Record{{Name: "T", Bins: []Bin{{
+			Codec: Codec{Name: "T", BinName: "lvl", Type: "custom.Level", Query: level},
+			Field: "Level",
+		}}}},
+		function: "TFromBinMap",
+	}
+
+	decode, err := buildCallableFunction(settings)
+	require.NoError(t, err)
+
+	ret, err := decode.(func(BinMap) (Member, error))(BinMap{"lvl": 2})
+	require.NoError(t, err)
+	assert.Equal(t, Member{Level: 2}, ret)
+
+	// the bin is decoded by the codec of its type which doesn't widen numbers
+	_, err = decode.(func(BinMap) (Member, error))(BinMap{"lvl": int64(2)})
+	assert.Error(t, err)
+
+	settings.function = "DecodeTLvl"
+	_, err = buildCallableFunction(settings)
+	assert.Error(t, err)
+}
+
+func TestGenerate_Func(t *testing.T) {
+	q := query.Query{IsTop: true, IsBuiltin: true, Type: "int"}
+
+	decode, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "int", decoder: "DecodeNumber", function: "DecodeNumber"})
+	require.NoError(t, err)
+
+	ret, err := decode.(func(interface{}) (int, error))(7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, ret)
+
+	encode, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "int", decoder: "DecodeNumber", function: "EncodeNumber"})
+	require.NoError(t, err)
+	assert.Equal(t, 7, encode.(func(int) interface{})(7))
+}
+
+func TestGenerate_Repo(t *testing.T) {
+	bins := []Bin{{
+		Codec: Codec{
+			Name: "T", BinName: "lvl", Type: "custom.Level",
+			Query: query.Query{IsTop: true, Type: "custom.Level", Kind: "int", IsBuiltin: true},
+		},
+		Field:    "Level",
+		Required: true,
+	}}
+
+	f, err := buildCallableFunction(buildSettings{
+		typeOfResult:          "custom.Member",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		records:               []Record{{Name: "T", Bins: bins, Repo: true}},
+		// methods of interpreted repository are returned as functions,
+		// the package may declare its own Client
+		source: `func Methods(c custom.Client) []interface{} {
+			r := NewTRepo(c)
+			return []interface{}{r.Get, r.Put, r.Delete, r.BatchGet}
+		}
+
+		type Client struct{}`,
+		function: "Methods",
+	})
+	require.NoError(t, err)
+
+	client := fakeClient{}
+	methods := f.(func(fakeClient) []interface{})(client)
+	get := methods[0].(func(stdcontext.Context, *Key) (Member, error))
+	put := methods[1].(func(stdcontext.Context, *Key, Member) error)
+	del := methods[2].(func(stdcontext.Context, *Key) (bool, error))
+	batchGet := methods[3].(func(stdcontext.Context, []*Key) ([]*Member, error))
+
+	ctx := stdcontext.Background()
+	first, second := &Key{Value: 1}, &Key{Value: 2}
+
+	require.NoError(t, put(ctx, first, Member{Level: 3}))
+	assert.Equal(t, BinMap{"lvl": 3}, client[1])
+
+	ret, err := get(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, Member{Level: 3}, ret)
+
+	_, err = get(ctx, second)
+	assert.Equal(t, errNotFound, err)
+
+	client[2] = BinMap{}
+	_, err = get(ctx, second)
+	assert.Equal(t, &molekula.DecodeError{Bin: "lvl", Path: "lvl", Expected: "custom.Level", Got: "<nil>", Missing: true}, err)
+
+	delete(client, 2)
+	members, err := batchGet(ctx, []*Key{first, second})
+	require.NoError(t, err)
+	assert.Equal(t, []*Member{{Level: 3}, nil}, members)
+
+	existed, err := del(ctx, first)
+	require.NoError(t, err)
+	assert.True(t, existed)
+	assert.Empty(t, client)
+
+	canceled, cancel := stdcontext.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, stdcontext.Canceled, put(canceled, first, Member{}))
+	assert.Empty(t, client)
+}
+
+func TestGenerate_Pointer(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Optional",
+		Fields: []query.Query{
+			{
+				Name: "Name", Alias: "name", Index: 1, Type: "*string", IsPointer: true,
+				Next: &query.Query{Index: 2, Type: "string", IsBuiltin: true},
+			},
+			{
+				Name: "Foo", Alias: "foo", Index: 1, Type: "*custom.Foo", IsPointer: true,
+				Next: &query.Query{
+					IsStruct: true,
+					Type:     "custom.Foo",
+					Index:    2,
+					Fields: []query.Query{
+						{Name: "Gender", Alias: "gender", Index: 3, Type: "string", IsBuiltin: true},
+						{Name: "ID", Alias: "id", Index: 3, Type: "int64", IsBuiltin: true},
+					},
+				},
+			},
+			{
+				Name: "Counters", Alias: "counters", Index: 1, Type: "map[string]*int", IsMap: true, KeyType: "string",
+				Next: &query.Query{
+					IsPointer: true, Index: 2, Type: "*int",
+					Next: &query.Query{Index: 3, Type: "int", IsBuiltin: true},
+				},
+			},
+		},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Optional",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Optional",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	name, one := "name", 1
+
+	tests := map[string]struct {
+		data  map[interface{}]interface{}
+		value Optional
+	}{
+		"nil values": {
+			data: map[interface{}]interface{}{
+				"name":     nil,
+				"foo":      nil,
+				"counters": map[interface{}]interface{}{"one": nil},
+			},
+			value: Optional{Counters: map[string]*int{"one": nil}},
+		},
+		"allocated values": {
+			data: map[interface{}]interface{}{
+				"name":     "name",
+				"foo":      map[interface{}]interface{}{"gender": "m", "id": int64(1)},
+				"counters": map[interface{}]interface{}{"one": 1},
+			},
+			value: Optional{
+				Name:     &name,
+				Foo:      &Foo{Gender: "m", ID: 1},
+				Counters: map[string]*int{"one": &one},
+			},
+		},
+	}
+
+	for title, tt := range tests {
+		ret, err := decode.(func(interface{}) (Optional, error))(tt.data)
+		require.NoError(t, err, title)
+		assert.Equal(t, tt.value, ret, title)
+
+		assert.Equal(t, tt.data, encode.(func(Optional) interface{})(tt.value), title)
+	}
+
+	ret, err := decode.(func(interface{}) (Optional, error))(map[interface{}]interface{}{
+		"counters": map[interface{}]interface{}{},
+	})
+	require.NoError(t, err, "missing keys")
+	assert.Equal(t, Optional{Counters: map[string]*int{}}, ret, "missing keys")
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	_, err := Generate(File{
+		Package: "foo",
+		Codecs:  []Codec{{Name: "T", BinName: "bin", Query: query.Query{IsTop: true, Type: "chan int"}}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported type "chan int"`)
+}
+
+func TestGenerate_NumericWidening(t *testing.T) {
+	q := query.Query{
+		IsTop:   true,
+		IsMap:   true,
+		Type:    "map[int8][]float32",
+		KeyType: "int8",
+		Next: &query.Query{
+			Index:   1,
+			IsArray: true,
+			Type:    "[]float32",
+			Next:    &query.Query{IsBuiltin: true, Index: 2, Type: "float32"},
+		},
+	}
+
+	widen, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "map[int8][]float32"})
+	require.NoError(t, err)
+
+	strict, err := buildCallableFunction(buildSettings{query: q, typeOfResult: "map[int8][]float32", strictNumeric: true})
+	require.NoError(t, err)
+
+	ret, err := widen.(func(interface{}) (map[int8][]float32, error))(map[interface{}]interface{}{
+		1:        []interface{}{0.5},
+		int64(2): []interface{}{float32(1.5), 2.5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[int8][]float32{1: {0.5}, 2: {1.5, 2.5}}, ret)
+
+	_, err = widen.(func(interface{}) (map[int8][]float32, error))(map[interface{}]interface{}{
+		200: []interface{}{0.5},
+	})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin[200]", Expected: "int8", Got: "int", Key: true}, err)
+
+	_, err = widen.(func(interface{}) (map[int8][]float32, error))(map[interface{}]interface{}{
+		1: []interface{}{1},
+	})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin[1][0]", Expected: "float32", Got: "int"}, err)
+
+	ret, err = strict.(func(interface{}) (map[int8][]float32, error))(map[interface{}]interface{}{
+		int8(1): []interface{}{float32(0.5)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[int8][]float32{1: {0.5}}, ret)
+
+	_, err = strict.(func(interface{}) (map[int8][]float32, error))(map[interface{}]interface{}{
+		1: []interface{}{float32(0.5)},
+	})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin[1]", Expected: "int8", Got: "int", Key: true}, err)
+
+	_, err = strict.(func(interface{}) (map[int8][]float32, error))(map[interface{}]interface{}{
+		int8(1): []interface{}{0.5},
+	})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin[1][0]", Expected: "float32", Got: "float64"}, err)
+}
+
+func TestGenerate_FieldOptions(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Foo",
+		Fields: []query.Query{
+			{Name: "Gender", Alias: "gender", Index: 1, Type: "string", IsBuiltin: true, Default: `"x"`, OmitEmpty: true},
+			{Name: "ID", Alias: "id", Index: 1, Type: "int64", IsBuiltin: true, Required: true, OmitEmpty: true},
+		},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	ret, err := decode.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, Foo{Gender: "x", ID: 1}, ret)
+
+	ret, err = decode.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"id": 1, "gender": "m"})
+	require.NoError(t, err)
+	assert.Equal(t, Foo{Gender: "m", ID: 1}, ret)
+
+	_, err = decode.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"gender": "m"})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.ID", Expected: "int64", Got: "<nil>", Missing: true}, err)
+
+	assert.Equal(t, map[interface{}]interface{}{"id": int64(1)}, encode.(func(Foo) interface{})(Foo{ID: 1}))
+	assert.Equal(t, map[interface{}]interface{}{"gender": "m"}, encode.(func(Foo) interface{})(Foo{Gender: "m"}))
+}
+
+func TestGenerate_MissingFields(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Foo",
+		Fields: []query.Query{
+			{Name: "Gender", Alias: "gender", Index: 1, Type: "string", IsBuiltin: true},
+			{Name: "ID", Alias: "id", Index: 1, Type: "int64", IsBuiltin: true},
+		},
+	}
+
+	tolerant, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	strict, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		strict:                true,
+	})
+	require.NoError(t, err)
+
+	ret, err := tolerant.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, Foo{ID: 1}, ret)
+
+	ret, err = tolerant.(func(interface{}) (Foo, error))(map[interface{}]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, Foo{}, ret)
+
+	_, err = tolerant.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"id": "1"})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.ID", Expected: "int64", Got: "string"}, err)
+
+	ret, err = strict.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"id": 1, "gender": "f"})
+	require.NoError(t, err)
+	assert.Equal(t, Foo{Gender: "f", ID: 1}, ret)
+
+	_, err = strict.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"id": 1})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.Gender", Expected: "string", Got: "<nil>", Missing: true}, err)
+
+	// an omitempty field is absent when it's empty, so it's never missing
+	q.Fields[0].OmitEmpty = true
+	settings := buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		strict:                true,
+	}
+	strict, err = buildCallableFunction(settings)
+	require.NoError(t, err)
+
+	settings.function = "EncodeTBin"
+	encode, err := buildCallableFunction(settings)
+	require.NoError(t, err)
+
+	ret, err = strict.(func(interface{}) (Foo, error))(encode.(func(Foo) interface{})(Foo{ID: 1}))
+	require.NoError(t, err)
+	assert.Equal(t, Foo{ID: 1}, ret)
+}
+
+func TestGenerate_UnknownKeys(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Foo",
+		Fields: []query.Query{
+			{Name: "Gender", Alias: "gender", Index: 1, Type: "string", IsBuiltin: true},
+			{Name: "ID", Alias: "id", Index: 1, Type: "int64", IsBuiltin: true},
+		},
+	}
+
+	ignore, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	reject, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Foo",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		rejectUnknown:         true,
+	})
+	require.NoError(t, err)
+
+	data := map[interface{}]interface{}{"id": 1, "gender": "f", "name": "Ann", 7: true}
+
+	ret, err := ignore.(func(interface{}) (Foo, error))(data)
+	require.NoError(t, err)
+	assert.Equal(t, Foo{Gender: "f", ID: 1}, ret)
+
+	_, err = reject.(func(interface{}) (Foo, error))(data)
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin", Expected: "custom.Foo", Got: "<nil>", Unknown: []string{`"name"`, "7"}}, err)
+
+	ret, err = reject.(func(interface{}) (Foo, error))(map[interface{}]interface{}{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, Foo{ID: 1}, ret)
+}
+
+func TestGenerate_RestField(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Extra",
+		Fields: []query.Query{
+			{Name: "Name", Alias: "name", Index: 1, Type: "string", IsBuiltin: true},
+			{
+				Name: "Rest", Alias: "rest", Index: 1, Type: "map[string]interface{}", IsMap: true, KeyType: "string", Rest: true,
+				Next: &query.Query{Index: 2, Type: "interface{}", IsBuiltin: true},
+			},
+		},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Extra",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		rejectUnknown:         true,
+	})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Extra",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	ret, err := decode.(func(interface{}) (Extra, error))(map[interface{}]interface{}{"name": "Ann", "age": 30, "tags": []interface{}{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, Extra{Name: "Ann", Rest: map[string]interface{}{"age": 30, "tags": []interface{}{"a"}}}, ret)
+
+	ret, err = decode.(func(interface{}) (Extra, error))(map[interface{}]interface{}{"name": "Ann"})
+	require.NoError(t, err)
+	assert.Equal(t, Extra{Name: "Ann"}, ret)
+
+	_, err = decode.(func(interface{}) (Extra, error))(map[interface{}]interface{}{1: 30})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.Rest[1]", Expected: "string", Got: "int", Key: true}, err)
+
+	assert.Equal(t,
+		map[interface{}]interface{}{"name": "Ann", "age": 30},
+		encode.(func(Extra) interface{})(Extra{Name: "Ann", Rest: map[string]interface{}{"age": 30, "name": "Bob"}}),
+	)
+}
+
+func TestGenerate_PromotedFields(t *testing.T) {
+	q := query.Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "custom.Account",
+		Fields: []query.Query{
+			{Name: "Name", Alias: "name", Index: 1, Type: "string", IsBuiltin: true},
+			{Name: "Audit.Created", Alias: "created", Index: 1, Type: "int64", IsBuiltin: true},
+		},
+	}
+
+	decode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Account",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+	})
+	require.NoError(t, err)
+
+	encode, err := buildCallableFunction(buildSettings{
+		query:                 q,
+		typeOfResult:          "custom.Account",
+		specialTypeDefinition: reflect.ValueOf((*Foo)(nil)),
+		function:              "EncodeTBin",
+	})
+	require.NoError(t, err)
+
+	account := Account{Audit: Audit{Created: 1600000000}, Name: "Ann"}
+	data := map[interface{}]interface{}{"name": "Ann", "created": int64(1600000000)}
+
+	ret, err := decode.(func(interface{}) (Account, error))(data)
+	require.NoError(t, err)
+	assert.Equal(t, account, ret)
+	assert.Equal(t, data, encode.(func(Account) interface{})(account))
+
+	_, err = decode.(func(interface{}) (Account, error))(map[interface{}]interface{}{"created": "now"})
+	assert.Equal(t, &molekula.DecodeError{Bin: "bin", Path: "bin.Audit.Created", Expected: "int64", Got: "string"}, err)
+}
+
+type Foo struct {
+	Gender string
+	ID     int64
+}
+
+type Bar struct {
+	Tags  []string
+	Users map[string][]Foo
+}
+
+type Optional struct {
+	Name     *string
+	Foo      *Foo
+	Counters map[string]*int
+}
+
+type Extra struct {
+	Name string
+	Rest map[string]interface{}
+}
+
+type Audit struct {
+	Created int64
+}
+
+type Account struct {
+	Audit
+	Name string
+}
+
+type Hash []byte
+
+type Event struct {
+	At      time.Time
+	Created time.Time
+	Updated time.Time
+	TTL     time.Duration
+}
+
+// Money is an amount of cents which is stored as a decimal string
+type Money int64
+
+func (m *Money) UnmarshalBin(data interface{}) error {
+	s, ok := data.(string)
+	if !ok {
+		return fmt.Errorf("money: expected string, got %T", data)
+	}
+
+	var units, cents int64
+	if _, err := fmt.Sscanf(s, "%d.%02d", &units, &cents); err != nil {
+		return fmt.Errorf("money: %w", err)
+	}
+	*m = Money(units*100 + cents)
+
+	return nil
+}
+
+func (m Money) MarshalBin() interface{} {
+	return fmt.Sprintf("%d.%02d", m/100, m%100)
+}
+
+type Payment struct {
+	Amounts []Money
+	IP      *net.IP
+}
+
+type Any struct {
+	Value  interface{}
+	Values []interface{}
+}
+
+type Level int
+
+type Status string
+
+type Member struct {
+	Level  Level
+	Status Status
+	Scores map[Level]float64
+}
+
+type buildSettings struct {
+	query                 query.Query
+	typeOfResult          string
+	specialTypeDefinition reflect.Value
+	// function is a name of generated function, DecodeTBin by default
+	function      string
+	strictNumeric bool
+	strict        bool
+	rejectUnknown bool
+	// records replace the codec of query
+	records []Record
+	// codecs are added to the file with records
+	codecs []Codec
+	// decoder is a Func of the codec
+	decoder string
+	// source is added to the generated code, it may declare the function
+	source string
+}
+
+// BinMap is a stub of aerospike.BinMap
+type BinMap map[string]interface{}
+
+// Key is a stub of aerospike.Key
+type Key struct {
+	Namespace string
+	Set       string
+	Value     interface{}
+}
+
+// ClientRecord, Error and policies are stubs of types of aerospike package which are used by the client
+type (
+	ClientRecord struct {
+		Key  *Key
+		Bins BinMap
+	}
+	Error       interface{ error }
+	BasePolicy  struct{}
+	WritePolicy struct{}
+	BatchPolicy struct{}
+)
+
+// errNotFound is returned by fakeClient for absent records
+var errNotFound = errors.New("key not found")
+
+// fakeClient is an in-memory client which stores bins by values of keys
+type fakeClient map[interface{}]BinMap
+
+func (c fakeClient) Get(_ *BasePolicy, key *Key, _ ...string) (*ClientRecord, Error) {
+	bins, ok := c[key.Value]
+	if !ok {
+		return nil, errNotFound
+	}
+
+	return &ClientRecord{Key: key, Bins: bins}, nil
+}
+
+func (c fakeClient) Put(_ *WritePolicy, key *Key, bins BinMap) Error {
+	c[key.Value] = bins
+	return nil
+}
+
+func (c fakeClient) Delete(_ *WritePolicy, key *Key) (bool, Error) {
+	_, ok := c[key.Value]
+	delete(c, key.Value)
+
+	return ok, nil
+}
+
+func (c fakeClient) BatchGet(_ *BatchPolicy, keys []*Key, _ ...string) ([]*ClientRecord, Error) {
+	records := make([]*ClientRecord, len(keys))
+	for i, key := range keys {
+		if bins, ok := c[key.Value]; ok {
+			records[i] = &ClientRecord{Key: key, Bins: bins}
+		}
+	}
+
+	return records, nil
+}
+
+// NewKey is a stub of aerospike.NewKey which accepts only keys of types supported by the client
+func NewKey(namespace, set string, value interface{}) (*Key, error) {
+	switch value.(type) {
+	case string, int, int64, []byte:
+		return &Key{Namespace: namespace, Set: set, Value: value}, nil
+	}
+
+	return nil, fmt.Errorf("invalid key %T", value)
+}
+
+// newInterpreter returns an interpreter with symbols of stdlib and molekula packages
+func newInterpreter() *interp.Interpreter {
+	i := interp.New(interp.Options{})
+	i.Use(stdlib.Symbols)
+	i.Use(interp.Exports{
+		"github.com/nikgalushko/molekula/molekula": {
+			"DecodeError":     reflect.ValueOf((*molekula.DecodeError)(nil)),
+			"NewDecodeError":  reflect.ValueOf(molekula.NewDecodeError),
+			"NewKeyError":     reflect.ValueOf(molekula.NewKeyError),
+			"NewMissingError": reflect.ValueOf(molekula.NewMissingError),
+			"NewLengthError":  reflect.ValueOf(molekula.NewLengthError),
+			"NewParseError":   reflect.ValueOf(molekula.NewParseError),
+			"NewEnumError":    reflect.ValueOf(molekula.NewEnumError),
+			"Text":            reflect.ValueOf(molekula.Text),
+			"NewUnknownError": reflect.ValueOf(molekula.NewUnknownError),
+			"Int":             reflect.ValueOf(molekula.Int),
+			"Uint":            reflect.ValueOf(molekula.Uint),
+			"Float":           reflect.ValueOf(molekula.Float),
+		},
+		"aerospike/aerospike": {
+			"BinMap":      reflect.ValueOf((*BinMap)(nil)),
+			"Key":         reflect.ValueOf((*Key)(nil)),
+			"NewKey":      reflect.ValueOf(NewKey),
+			"Record":      reflect.ValueOf((*ClientRecord)(nil)),
+			"Error":       reflect.ValueOf((*Error)(nil)),
+			"BasePolicy":  reflect.ValueOf((*BasePolicy)(nil)),
+			"WritePolicy": reflect.ValueOf((*WritePolicy)(nil)),
+			"BatchPolicy": reflect.ValueOf((*BatchPolicy)(nil)),
+		},
+	})
+
+	return i
+}
+
+func buildCallableFunction(s buildSettings) (interface{}, error) {
+	file := File{
+		Package: "foo",
+		Codecs:  []Codec{{Name: "T", BinName: "bin", Func: s.decoder, StrictNumeric: s.strictNumeric, Strict: s.strict, RejectUnknown: s.rejectUnknown, Query: s.query}},
+	}
+	if s.records != nil {
+		file.Codecs, file.Records, file.Client = s.codecs, s.records, "aerospike"
+	}
+	i := newInterpreter()
+
+	if s.specialTypeDefinition.IsValid() {
+		// symbols are keyed by "import path/package name"
+		custom := make(map[string]map[string]reflect.Value)
+		custom["custom/custom"] = make(map[string]reflect.Value)
+		custom["custom/custom"]["Foo"] = s.specialTypeDefinition
+		custom["custom/custom"]["Bar"] = reflect.ValueOf((*Bar)(nil))
+		custom["custom/custom"]["Optional"] = reflect.ValueOf((*Optional)(nil))
+		custom["custom/custom"]["Extra"] = reflect.ValueOf((*Extra)(nil))
+		custom["custom/custom"]["Account"] = reflect.ValueOf((*Account)(nil))
+		custom["custom/custom"]["Hash"] = reflect.ValueOf((*Hash)(nil))
+		custom["custom/custom"]["Event"] = reflect.ValueOf((*Event)(nil))
+		custom["custom/custom"]["Money"] = reflect.ValueOf((*Money)(nil))
+		custom["custom/custom"]["Payment"] = reflect.ValueOf((*Payment)(nil))
+		custom["custom/custom"]["Any"] = reflect.ValueOf((*Any)(nil))
+		custom["custom/custom"]["Level"] = reflect.ValueOf((*Level)(nil))
+		custom["custom/custom"]["Status"] = reflect.ValueOf((*Status)(nil))
+		custom["custom/custom"]["Member"] = reflect.ValueOf((*Member)(nil))
+		custom["custom/custom"]["Client"] = reflect.ValueOf((*fakeClient)(nil))
+
+		i.Use(custom)
+
+		file.Imports = []parser.Import{{Path: "custom"}, {Path: "net"}, {Path: "time"}}
+	}
+
+	src, err := Generate(file)
+	if err != nil {
+		return nil, err
+	}
+
+	//fmt.Println(string(src))
+
+	// T is an alias of result type, because a function name is generated from a type name
+	_, err = i.Eval(fmt.Sprintf("%s\ntype T = %s\n%s", src, s.typeOfResult, s.source))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.function == "" {
+		s.function = "DecodeTBin"
 	}
 
-	v, err := i.Eval("foo.wrapper")
+	v, err := i.Eval("foo." + s.function)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,251 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nikgalushko/molekula/internal/query"
+)
+
+// context is a description of place where the value of query is located
+type context struct {
+	// Bin is aerospikes' bin name which the value belongs to
+	Bin string
+	// Path is a format of path to the value like config[%#v].Users[%d].ID
+	Path string
+	// PathArgs is a list of variables which are arguments of Path
+	PathArgs []string
+	// StrictNumeric is true if numbers are decoded only from values of exactly the same type
+	StrictNumeric bool
+	// Strict is true if absent fields of structs are reported as errors
+	Strict bool
+	// RejectUnknown is true if keys of structs which don't belong to any field are reported as errors
+	RejectUnknown bool
+}
+
+// with returns a context of nested value
+func (c context) with(path, arg string) context {
+	c.Path += path
+	if arg != "" {
+		c.PathArgs = append(append(make([]string, 0, len(c.PathArgs)+1), c.PathArgs...), arg)
+	}
+
+	return c
+}
+
+// node is a query with a context which is required by templates
+type node struct {
+	query.Query
+	context
+	Next   *node
+	Fields []node
+}
+
+func newNode(q query.Query, ctx context) node {
+	n := node{
+		Query:   q,
+		context: ctx,
+	}
+
+	switch {
+	case q.IsArray, q.IsFixedArray:
+		next := newNode(*q.Next, ctx.with("[%d]", fmt.Sprintf("i_%d", q.Index)))
+		n.Next = &next
+	case q.IsMap:
+		next := newNode(*q.Next, ctx.with("[%#v]", fmt.Sprintf("raw_key_%d", q.Index)))
+		n.Next = &next
+	case q.IsPointer:
+		next := newNode(*q.Next, ctx)
+		n.Next = &next
+	case q.IsStruct:
+		n.Fields = make([]node, 0, len(q.Fields))
+		for _, f := range q.Fields {
+			n.Fields = append(n.Fields, newNode(f, ctx.with("."+f.Name, "")))
+		}
+	}
+
+	return n
+}
+
+// HasText returns true if the value or any nested value is encoded by its MarshalText method
+func (n node) HasText() bool {
+	if n.IsCustom && n.IsText {
+		return true
+	}
+
+	if n.Next != nil && n.Next.HasText() {
+		return true
+	}
+
+	for _, f := range n.Fields {
+		if f.HasText() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RestField returns a field of struct node which collects unknown keys or nil
+func (n node) RestField() *node {
+	for i := range n.Fields {
+		if n.Fields[i].Rest {
+			return &n.Fields[i]
+		}
+	}
+
+	return nil
+}
+
+// knownKeys returns a comma separated list of quoted keys of struct node fields
+func knownKeys(n node) string {
+	keys := make([]string, 0, len(n.Fields))
+	for _, f := range n.Fields {
+		if !f.Rest {
+			keys = append(keys, strconv.Quote(f.Alias))
+		}
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+// input returns a name of variable which holds a value to decode
+func input(n node) string {
+	if n.IsTop {
+		return "data"
+	}
+
+	return fmt.Sprintf("raw_value_%d", n.Index-1)
+}
+
+// decodeError returns an expression which creates molekula.DecodeError for the input value of node
+func decodeError(n node) string {
+	return newError("NewDecodeError", n, n.Type, input(n))
+}
+
+// lengthError returns an expression which creates molekula.DecodeError for the list of wrong length
+func lengthError(n node) string {
+	return newError("NewLengthError", n, n.Type, fmt.Sprintf("len(value_%d)", n.Index))
+}
+
+// parseError returns an expression which creates molekula.DecodeError for the input value of node
+// which has a valid dynamic type but can't be parsed
+func parseError(n node, err string) string {
+	return newError("NewParseError", n, n.Type, input(n)+", "+err)
+}
+
+// keyError returns an expression which creates molekula.DecodeError for the current key of map node.
+// The path of a key is the path of its value.
+func keyError(n node) string {
+	return newError("NewKeyError", *n.Next, n.KeyType, fmt.Sprintf("raw_key_%d", n.Index))
+}
+
+// restKeyError returns an expression which creates molekula.DecodeError for the current unknown key of struct node
+// which can't be collected by the rest field
+func restKeyError(n node) string {
+	rest := *n.RestField()
+	key := fmt.Sprintf("raw_key_%d", n.Index)
+	rest.context = rest.with("[%#v]", key)
+
+	return newError("NewKeyError", rest, "string", key)
+}
+
+// unknownError returns an expression which creates molekula.DecodeError for unknown keys of struct node
+func unknownError(n node) string {
+	return newError("NewUnknownError", n, n.Type, fmt.Sprintf("unknown_%d", n.Index))
+}
+
+// numbers is a mapping of numeric types to a name of molekula function which converts a value and a bit size
+var numbers = map[string]struct {
+	convert string
+	bitSize int
+}{
+	"int":     {"Int", 0},
+	"int8":    {"Int", 8},
+	"int16":   {"Int", 16},
+	"int32":   {"Int", 32},
+	"rune":    {"Int", 32},
+	"int64":   {"Int", 64},
+	"uint":    {"Uint", 0},
+	"uint8":   {"Uint", 8},
+	"byte":    {"Uint", 8},
+	"uint16":  {"Uint", 16},
+	"uint32":  {"Uint", 32},
+	"uint64":  {"Uint", 64},
+	"float32": {"Float", 32},
+	"float64": {"Float", 64},
+}
+
+// assertion returns statements which declare the variable out of type typ and ok.
+// The value is asserted to the built-in kind of named type and converted to typ, an empty kind means typ itself.
+// Unless the node is strict, numbers are converted from any type of the same kind if the value fits.
+func assertion(n node, typ, kind, in, out string) string {
+	if kind == "" {
+		kind = typ
+	}
+
+	number, ok := numbers[kind]
+	switch {
+	case (!ok || n.StrictNumeric) && kind == typ:
+		return fmt.Sprintf("%s, ok := %s.(%s)", out, in, typ)
+	case !ok || n.StrictNumeric:
+		return fmt.Sprintf("%s_value, ok := %s.(%s)\n%s := %s(%s_value)", out, in, kind, out, typ, out)
+	}
+
+	return fmt.Sprintf("%s_number, ok := molekula.%s(%s, %d)\n%s := %s(%s_number)",
+		out, number.convert, in, number.bitSize, out, typ, out)
+}
+
+// kind returns a built-in type of node, it differs from the type for named types like type Level int
+func kind(n node) string {
+	if n.Kind != "" {
+		return n.Kind
+	}
+
+	return n.Type
+}
+
+// enumError returns an expression which creates molekula.DecodeError for the input value of node
+// which isn't one of declared values
+func enumError(n node) string {
+	return newError("NewEnumError", n, n.Type, input(n))
+}
+
+// missingError returns an expression which creates molekula.DecodeError for the absent value of node
+func missingError(n node) string {
+	return newError("NewMissingError", n, n.Type, "")
+}
+
+// newError returns an expression which calls the constructor with a path of node.
+// The got argument is omitted if it's empty.
+func newError(constructor string, n node, expected, got string) string {
+	args := []string{strconv.Quote(n.Bin), strconv.Quote(expected)}
+	if got != "" {
+		args = append(args, got)
+	}
+	args = append(args, strconv.Quote(n.Path))
+
+	return fmt.Sprintf("molekula.%s(%s)", constructor, strings.Join(append(args, n.PathArgs...), ", "))
+}
+
+// notEmpty returns a condition which is true if the value of node stored in the variable isn't empty.
+// Empty values are false, 0, "", nil and slices or maps of zero length; structs are never empty.
+func notEmpty(n node, variable string) string {
+	switch {
+	case n.IsArray, n.IsFixedArray, n.IsMap, n.IsBlob:
+		return fmt.Sprintf("len(%s) != 0", variable)
+	case n.IsPointer, n.Type == "interface{}":
+		return variable + " != nil"
+	case n.IsTime:
+		return "!" + variable + ".IsZero()"
+	case kind(n) == "string":
+		return variable + ` != ""`
+	case kind(n) == "bool":
+		return variable
+	case n.IsBuiltin, n.IsDuration:
+		return variable + " != 0"
+	}
+
+	return "true"
+}
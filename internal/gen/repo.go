@@ -0,0 +1,103 @@
+package gen
+
+import "text/template"
+
+// the client doesn't accept contexts, so a context is checked only before a request.
+// Errors of the client are returned only if they aren't nil, so an error interface never holds a nil value.
+const repo = `
+// {{.Name}}Repo reads and writes {{.Name}} records with the client.
+type {{.Name}}Repo struct {
+	client MolekulaClient
+}
+
+// New{{.Name}}Repo returns a repository of {{.Name}} records.
+func New{{.Name}}Repo(client MolekulaClient) *{{.Name}}Repo {
+	return &{{.Name}}Repo{client: client}
+}
+
+// Get reads the record of the key and decodes {{.Name}} from its bins.
+func (r *{{.Name}}Repo) Get(ctx context.Context, key *aerospike.Key) ({{.Name}}, error) {
+	if err := ctx.Err(); err != nil {
+		return {{.Name}}{}, err
+	}
+
+	record, err := r.client.Get(nil, key)
+	if err != nil {
+		return {{.Name}}{}, err
+	}
+
+	return {{.Name}}FromBinMap(record.Bins)
+}
+
+// Put encodes v to bins and writes them to the record of the key.
+func (r *{{.Name}}Repo) Put(ctx context.Context, key *aerospike.Key, v {{.Name}}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := r.client.Put(nil, key, {{.Name}}ToBinMap(v)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes the record of the key, it returns false if the record doesn't exist.
+func (r *{{.Name}}Repo) Delete(ctx context.Context, key *aerospike.Key) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	existed, err := r.client.Delete(nil, key)
+	if err != nil {
+		return false, err
+	}
+
+	return existed, nil
+}
+
+// BatchGet reads records of the keys and decodes them in the order of keys,
+// records which don't exist are nil.
+func (r *{{.Name}}Repo) BatchGet(ctx context.Context, keys []*aerospike.Key) ([]*{{.Name}}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	records, err := r.client.BatchGet(nil, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*{{.Name}}, len(records))
+	for i, record := range records {
+		if record == nil {
+			continue
+		}
+
+		v, err := {{.Name}}FromBinMap(record.Bins)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = &v
+	}
+
+	return ret, nil
+}
+`
+
+// client is a subset of methods of *aerospike.Client, so repositories work with the client and with fakes.
+// It's prefixed, because packages which use the client often declare their own Client.
+const client = `
+// MolekulaClient is a part of *aerospike.Client which is used by repositories.
+type MolekulaClient interface {
+	Get(policy *aerospike.BasePolicy, key *aerospike.Key, binNames ...string) (*aerospike.Record, aerospike.Error)
+	Put(policy *aerospike.WritePolicy, key *aerospike.Key, bins aerospike.BinMap) aerospike.Error
+	Delete(policy *aerospike.WritePolicy, key *aerospike.Key) (bool, aerospike.Error)
+	BatchGet(policy *aerospike.BatchPolicy, keys []*aerospike.Key, binNames ...string) ([]*aerospike.Record, aerospike.Error)
+}
+`
+
+func init() {
+	template.Must(tmpl.New("REPO").Parse(repo))
+	template.Must(tmpl.New("CLIENT").Parse(client))
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// maxBinName is the longest bin name in bytes which is accepted by aerospike server
+const maxBinName = 15
+
+// binNameError returns a reason why the name can't be used as a bin name or nil.
+// Names are restricted to ASCII letters, digits, '_' and '-', so they are safe for the server
+// and for names of generated functions.
+func binNameError(name string) error {
+	if name == "" {
+		return fmt.Errorf("bin name is empty")
+	}
+
+	if len(name) > maxBinName {
+		return fmt.Errorf("bin name is %d bytes long, aerospike allows at most %d", len(name), maxBinName)
+	}
+
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return fmt.Errorf("bin name contains %q, only ASCII letters, digits, '_' and '-' are allowed", r)
+		}
+	}
+
+	return nil
+}
+
+// binOwner is a declaration which claims a bin name
+type binOwner struct {
+	name string
+	pos  token.Pos
+}
+
+// claimBin reports an invalid bin name or a bin name which is already claimed by another declaration
+// of the same scope like a set or a record. It returns false if the name is reported.
+func (v *visitor) claimBin(scope map[string]binOwner, bin string, owner binOwner) bool {
+	if err := binNameError(bin); err != nil {
+		v.errorf("%s", err)
+		return false
+	}
+
+	if prev, ok := scope[bin]; ok {
+		pos := v.pkg.Fset.Position(prev.pos)
+		v.errorf("bin name is already used by %s at %s:%d", prev.name, filepath.Base(pos.Filename), pos.Line)
+		return false
+	}
+	scope[bin] = owner
+
+	return true
+}
+
+// claimFunc reports a decoder which is already generated for another declaration of the package.
+// Names of decoders are derived from bin names, so different bins like a_b and aB may clash.
+func (v *visitor) claimFunc(decoder string, owner binOwner) {
+	if prev, ok := v.funcs[decoder]; ok {
+		pos := v.pkg.Fset.Position(prev.pos)
+		v.errorf("decoder %s is already generated for %s at %s:%d", decoder, prev.name, filepath.Base(pos.Filename), pos.Line)
+		return
+	}
+	v.funcs[decoder] = owner
+}
+
+// Camel converts a bin name to CamelCase for names of generated functions: config_version -> ConfigVersion
+func Camel(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// Directive is a configuration of type which is parsed from a comment like
+// 'molekula:bin=profile set=users strict numeric=widen func=DecodeProfile'.
+// Options are separated by spaces or commas, a leading option without a value is the bin name:
+// 'molekula:profile,strict' is the same as 'molekula:bin=profile strict'.
+type Directive struct {
+	// BinName is aerospikes' bin name, it's empty for records
+	BinName string
+	// Record is true if the type is a struct which is stored as a whole record:
+	// every field is a separate bin named by the field alias.
+	Record bool
+	// Enum is true if values of the type are restricted by its declared constants.
+	// Enums don't have own codecs, they are validated by codecs of other types.
+	Enum bool
+	// Set is a name of aerospikes' set which the type belongs to, bin names are unique per set
+	Set string
+	// Namespace is a namespace of records, it's required for records with a key field
+	Namespace string
+	// Repo is true if a repository of records should be generated
+	Repo bool
+	// Func is a name of generated decoder, the encoder is named with Encode prefix instead of Decode.
+	// Functions are named Decode<Name><BinName> and Encode<Name><BinName> if it's empty.
+	Func string
+	// StrictNumeric is true if numbers are decoded only from values of exactly the same type.
+	// Otherwise any integer is accepted for an integer type and float64 for float32 if the value fits.
+	StrictNumeric bool
+	// Strict is true if absent fields of structs are reported as errors.
+	// Otherwise absent fields keep zero values or tag defaults.
+	Strict bool
+	// RejectUnknown is true if keys of structs which don't belong to any field are reported as errors.
+	// Otherwise they are ignored unless the struct has a rest field.
+	RejectUnknown bool
+	// Unexported is true if unexported fields of structs declared in the package are decoded.
+	// Unexported fields of other packages are never accessible to the generated code.
+	Unexported bool
+}
+
+// newDirective parses a text which follows 'molekula:' in a comment.
+// Invalid options are returned as errors, the rest of options are applied.
+func newDirective(text string) (Directive, []error) {
+	var (
+		d    Directive
+		errs []error
+	)
+
+	options := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	seen := make(map[string]bool, len(options))
+	for i, option := range options {
+		key, value, hasValue := strings.Cut(option, "=")
+		if i == 0 && !hasValue && !flags[key] {
+			key, value, hasValue = "bin", option, true
+		}
+
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("option %q is repeated", key))
+			continue
+		}
+		seen[key] = true
+
+		if hasValue && value == "" {
+			errs = append(errs, fmt.Errorf("option %q has no value", key))
+			continue
+		}
+
+		switch {
+		case option == "record":
+			d.Record = true
+		case option == "enum":
+			d.Enum = true
+		case option == "strict":
+			d.Strict = true
+		case option == "unexported":
+			d.Unexported = true
+		case option == "repo":
+			d.Repo = true
+		case key == "bin" && hasValue:
+			d.BinName = value
+		case key == "set" && hasValue:
+			d.Set = value
+		case key == "ns" && hasValue:
+			d.Namespace = value
+		case key == "func" && hasValue:
+			d.Func = value
+		case option == "numeric=strict":
+			d.StrictNumeric = true
+		case option == "numeric=widen":
+			d.StrictNumeric = false
+		case option == "unknown=ignore":
+			d.RejectUnknown = false
+		case option == "unknown=reject":
+			d.RejectUnknown = true
+		default:
+			errs = append(errs, fmt.Errorf("unknown option %q", option))
+		}
+	}
+
+	if d.Func != "" && (!token.IsIdentifier(d.Func) || !strings.HasPrefix(d.Func, "Decode")) {
+		errs = append(errs, fmt.Errorf("func %q must be an identifier which starts with Decode", d.Func))
+	}
+
+	// enums don't have own codecs, so options of codecs aren't allowed for them
+	if d.Enum && !d.Record {
+		for _, key := range []string{"bin", "set", "func", "strict", "unexported", "numeric", "unknown"} {
+			if seen[key] {
+				errs = append(errs, fmt.Errorf("option %q is not allowed for enums, they don't have codecs", key))
+			}
+		}
+	}
+
+	if d.Record {
+		for _, key := range []string{"bin", "func", "enum"} {
+			if seen[key] {
+				errs = append(errs, fmt.Errorf("option %q is not allowed for records, their fields are bins", key))
+			}
+		}
+	} else {
+		for _, key := range []string{"ns", "repo"} {
+			if seen[key] {
+				errs = append(errs, fmt.Errorf("option %q is allowed only for records", key))
+			}
+		}
+	}
+
+	return d, errs
+}
+
+// flags are options without values, a leading option which isn't a flag is the bin name
+var flags = map[string]bool{
+	"record":     true,
+	"enum":       true,
+	"strict":     true,
+	"unexported": true,
+	"repo":       true,
+}
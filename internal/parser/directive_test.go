@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDirective(t *testing.T) {
+	testCases := []struct {
+		text      string
+		directive Directive
+		errs      []error
+	}{
+		{text: "profile", directive: Directive{BinName: "profile"}},
+		{text: "profile,numeric=strict,unknown=reject", directive: Directive{BinName: "profile", StrictNumeric: true, RejectUnknown: true}},
+		{
+			text:      "bin=profile set=users strict numeric=widen func=DecodeProfile",
+			directive: Directive{BinName: "profile", Set: "users", Strict: true, Func: "DecodeProfile"},
+		},
+		{text: "strict bin=profile unexported", directive: Directive{BinName: "profile", Strict: true, Unexported: true}},
+		{text: "record ns=prod set=users", directive: Directive{Record: true, Namespace: "prod", Set: "users"}},
+		{text: "record repo", directive: Directive{Record: true, Repo: true}},
+		{
+			text:      "profile ns=prod repo",
+			directive: Directive{BinName: "profile", Namespace: "prod", Repo: true},
+			errs:      []error{errors.New(`option "ns" is allowed only for records`), errors.New(`option "repo" is allowed only for records`)},
+		},
+		{text: "enum", directive: Directive{Enum: true}},
+		{
+			text:      "bin=level enum",
+			directive: Directive{BinName: "level", Enum: true},
+			errs:      []error{errors.New(`option "bin" is not allowed for enums, they don't have codecs`)},
+		},
+		{text: ",strict", directive: Directive{Strict: true}},
+		{
+			text:      "profile numeric=fast strict=yes",
+			directive: Directive{BinName: "profile"},
+			errs:      []error{errors.New(`unknown option "numeric=fast"`), errors.New(`unknown option "strict=yes"`)},
+		},
+		{
+			text:      "profile bin=other set=",
+			directive: Directive{BinName: "profile"},
+			errs:      []error{errors.New(`option "bin" is repeated`), errors.New(`option "set" has no value`)},
+		},
+		{
+			text:      "profile func=Profile",
+			directive: Directive{BinName: "profile", Func: "Profile"},
+			errs:      []error{errors.New(`func "Profile" must be an identifier which starts with Decode`)},
+		},
+		{
+			text:      "record func=DecodeUser",
+			directive: Directive{Record: true, Func: "DecodeUser"},
+			errs:      []error{errors.New(`option "func" is not allowed for records, their fields are bins`)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			directive, errs := newDirective(tc.text)
+			assert.Equal(t, tc.directive, directive)
+			assert.Equal(t, tc.errs, errs)
+		})
+	}
+}
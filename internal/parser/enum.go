@@ -0,0 +1,100 @@
+package parser
+
+import (
+	goast "go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/nikgalushko/molekula/internal/ast"
+)
+
+// enums returns types of the package and its dependencies which are annotated with 'molekula:enum'
+func enums(pkg *packages.Package) map[*types.TypeName]bool {
+	ret := make(map[*types.TypeName]bool)
+	packages.Visit([]*packages.Package{pkg}, nil, func(p *packages.Package) {
+		if p.TypesInfo == nil {
+			return
+		}
+
+		for _, file := range p.Syntax {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*goast.GenDecl)
+				if !ok || decl.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range decl.Specs {
+					spec := spec.(*goast.TypeSpec)
+					text, ok := parseDirective(specDoc(decl, spec))
+					if !ok {
+						continue
+					}
+
+					if directive, _ := newDirective(text); !directive.Enum {
+						continue
+					}
+
+					if name, ok := p.TypesInfo.Defs[spec.Name].(*types.TypeName); ok {
+						ret[name] = true
+					}
+				}
+			}
+		}
+	})
+
+	return ret
+}
+
+// named returns a description of named type which underlying type is built-in.
+// Values of enum are Go literals of constants of the type declared in its package.
+func (v *visitor) named(n *types.Named, b *types.Basic) ast.Type {
+	underlying, ok := v.parseType(b).(ast.BuiltIn)
+	if !ok {
+		return nil
+	}
+
+	name := types.TypeString(n, v.qualifier)
+	if !v.enums[n.Obj()] {
+		return ast.Named{Name: name, Underlying: underlying}
+	}
+
+	if b.Info()&(types.IsString|types.IsInteger) == 0 {
+		return v.errorf("enum %s must be a string or an integer type", name)
+	}
+
+	var consts []*types.Const
+	scope := n.Obj().Pkg().Scope()
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*types.Const); ok && types.Identical(c.Type(), n) {
+			consts = append(consts, c)
+		}
+	}
+
+	if len(consts) == 0 {
+		return v.errorf("enum %s has no declared constants", name)
+	}
+
+	// values are listed in order of declaration, constants with the same value are listed once
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+	enum := ast.Named{Name: name, Underlying: underlying}
+	seen := make(map[string]bool, len(consts))
+	for _, c := range consts {
+		literal := c.Val().ExactString()
+		if c.Val().Kind() == constant.String {
+			literal = strconv.Quote(constant.StringVal(c.Val()))
+		}
+
+		if !seen[literal] {
+			seen[literal] = true
+			enum.Enum = append(enum.Enum, literal)
+		}
+	}
+
+	return enum
+}
@@ -1,39 +1,199 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
 	goast "go/ast"
+	"go/token"
+	"go/types"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
+	"golang.org/x/tools/go/packages"
+
 	"github.com/nikgalushko/molekula/internal/ast"
 )
 
 type Object struct {
-	// BinName is aerospikes' bin name which parsed from tag 'molekula'
-	BinName string
+	// Directive is a configuration of the type which is parsed from the 'molekula:' comment
+	Directive
+	// Name is a name of declared Go type
+	Name string
 	// Type is a type description
 	Type ast.Type
+	// Methods is true if methods can be declared on the type, aliases, pointers and interfaces can't have them
+	Methods bool
+	// Imports is a list of packages which types are used by Type
+	Imports []Import
+}
+
+// Import is a package which is imported by the generated code
+type Import struct {
+	// Path is an import path of the package
+	Path string
+	// Name is a local name of the package if it differs from the package name,
+	// packages with the same name are imported under unique names
+	Name string
+}
+
+// Diagnostic is an error in a declaration of type which can't be stored in a bin
+type Diagnostic struct {
+	// Pos is a position of declaration which caused the error
+	Pos     token.Position
+	BinName string
+	Message string
+}
+
+// Error returns a compiler-style message: file:line:col: bin "name": message.
+// The bin is omitted for errors of records which don't belong to any of their bins.
+func (d Diagnostic) Error() string {
+	if d.BinName == "" {
+		return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+	}
+
+	return fmt.Sprintf("%s: bin %q: %s", d.Pos, d.BinName, d.Message)
+}
+
+// Load loads a package located in the dir with its syntax and type information.
+// Type errors are not fatal: the package may use code generated by previous run which is outdated or absent.
+func Load(dir string) (*packages.Package, error) {
+	// dependencies are type-checked from source, so loading doesn't depend on export data format of the toolchain
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo |
+			packages.NeedImports | packages.NeedDeps,
+		Dir: dir,
+	}
+
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package in %s, found %d", dir, len(pkgs))
+	}
+
+	pkg := pkgs[0]
+	for _, e := range pkg.Errors {
+		if e.Kind != packages.TypeError {
+			return nil, e
+		}
+	}
+
+	return pkg, nil
 }
 
-// Parse returns a list of Objects which tagged 'molekula' in a input file
-func Parse(file *goast.File) []Object {
-	v := &visitor{}
-	goast.Walk(v, file)
+// Parse returns a list of Objects which tagged 'molekula' in a input package.
+// Named types are resolved across all files of the package and imported packages.
+// Types which can't be stored in a bin are reported as diagnostics and aren't included in Objects.
+func Parse(pkg *packages.Package) ([]Object, []Diagnostic) {
+	v := &visitor{
+		pkg:       pkg,
+		names:     fieldNames(pkg),
+		generated: generatedFiles(pkg),
+		enums:     enums(pkg),
+		sets:      make(map[string]map[string]binOwner),
+		funcs:     make(map[string]binOwner),
+		// packages which are referred by the generated code keep their names
+		importNames: map[string]string{"time": "time", "context": "context"},
+	}
+	for _, file := range pkg.Syntax {
+		goast.Walk(v, file)
+	}
 
-	return v.objects
+	return v.objects, v.diagnostics
 }
 
 type visitor struct {
+	pkg            *packages.Package
 	objects        []Object
+	diagnostics    []Diagnostic
 	currentBinName *string
+	// directive is a directive of the type which is being parsed and errors of its options
+	directive       Directive
+	directiveErrors []error
+	// pos is a position of declaration which is being parsed
+	pos token.Pos
+	// imports is a set of packages which are used by a type of current object
+	imports map[string]Import
+	// importNames is a mapping of import paths to local names which are unique in the generated file
+	importNames map[string]string
+	// visiting is a set of named types which are being parsed, it protects from recursive types
+	visiting map[*types.Named]bool
+	// names is a mapping of positions of field names to all names of their declarations like A, B int
+	names map[token.Pos][]string
+	// unexported is true if unexported fields of the package are decoded for current object
+	unexported bool
+	// timeFormat is a format of time.Time values of current field which is set by a tag option
+	timeFormat string
+	// generated is a set of files of the package which are generated by molekula
+	generated map[string]bool
+	// enums is a set of types of the package and its dependencies which are annotated as enums
+	enums map[*types.TypeName]bool
+	// bins is true if fields of the next parsed struct are bins of a record
+	bins bool
+	// sets is a mapping of set names to bins which are claimed by types of the set
+	sets map[string]map[string]binOwner
+	// funcs is a mapping of names of generated decoders to declarations which claim them
+	funcs map[string]binOwner
+	// name is a name of current object
+	name string
+}
+
+// header is the first line of files which are generated by molekula
+const header = "// Code generated by molekula. DO NOT EDIT."
+
+// generatedFiles returns names of files of the package which are generated by molekula.
+// Methods of these files are replaced by the next generation, so they aren't custom codecs.
+func generatedFiles(pkg *packages.Package) map[string]bool {
+	files := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		if len(file.Comments) > 0 && file.Comments[0].List[0].Text == header {
+			files[pkg.Fset.Position(file.Pos()).Filename] = true
+		}
+	}
+
+	return files
 }
 
-func parseBinName(node *goast.GenDecl) (string, bool) {
-	if node.Doc == nil || len(node.Doc.List) == 0 {
+// fieldNames returns names of struct field declarations with multiple names in the package and its dependencies.
+// Type information doesn't keep declarations, every name is a separate field.
+func fieldNames(pkg *packages.Package) map[token.Pos][]string {
+	names := make(map[token.Pos][]string)
+	packages.Visit([]*packages.Package{pkg}, nil, func(p *packages.Package) {
+		for _, file := range p.Syntax {
+			goast.Inspect(file, func(n goast.Node) bool {
+				field, ok := n.(*goast.Field)
+				if !ok || len(field.Names) < 2 {
+					return true
+				}
+
+				list := make([]string, 0, len(field.Names))
+				for _, name := range field.Names {
+					list = append(list, name.Name)
+				}
+				for _, name := range field.Names {
+					names[name.Pos()] = list
+				}
+
+				return true
+			})
+		}
+	})
+
+	return names
+}
+
+// parseDirective returns a text which follows 'molekula:' in the comment group
+func parseDirective(doc *goast.CommentGroup) (string, bool) {
+	if doc == nil || len(doc.List) == 0 {
 		return "", false
 	}
 
-	for _, v := range node.Doc.List {
+	for _, v := range doc.List {
 		comment := v.Text
 
 		if len(comment) > 2 {
@@ -57,84 +217,848 @@ func parseBinName(node *goast.GenDecl) (string, bool) {
 	return "", false
 }
 
-func parseStruct(node *goast.StructType) []ast.StructField {
-	description := make([]ast.StructField, len(node.Fields.List))
+func (v *visitor) parseStruct(s *types.Struct) []ast.StructField {
+	description := make([]ast.StructField, 0, s.NumFields())
+	// promoted is a list of fields of embedded structs, they are shadowed by own fields of the struct
+	var promoted []promotedField
+
+	pos, timeFormat, binName := v.pos, v.timeFormat, v.currentBinName
+	defer func() { v.pos, v.timeFormat, v.currentBinName = pos, timeFormat, binName }()
+
+	// only fields of the record itself are bins, nested structs are values of their bins
+	bins := v.bins
+	v.bins = false
+
+	rest, key := "", ""
+	// positions of record fields, bin names are validated after promotion
+	positions := make(map[string]token.Pos)
+	// keys of own fields of a plain struct, duplicates of records are reported as bins
+	keys := make(map[string]string)
 
-	for i, f := range node.Fields.List {
-		description[i] = ast.StructField{
-			Type:  pasrseGoASTType(f.Type),
-			Name:  f.Names[0].Name,
-			Alias: strings.ToLower(f.Names[0].Name),
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		v.pos = f.Pos()
+		v.timeFormat = ""
+
+		field := ast.StructField{
+			Name:  f.Name(),
+			Alias: strings.ToLower(f.Name()),
+		}
+
+		tag, ok := reflect.StructTag(s.Tag(i)).Lookup("molekula")
+		if tag == "-" {
+			continue
+		}
+
+		if bins {
+			bin := field.Alias
+			if name := strings.Split(tag, ",")[0]; name != "" {
+				bin = name
+			}
+			v.currentBinName = &bin
+		}
+
+		if names := v.names[f.Pos()]; ok && len(names) > 1 {
+			// the tag is reported once for the whole declaration
+			if names[0] == f.Name() {
+				v.errorf("field %s: tag is shared by fields %s, declare them separately", f.Name(), strings.Join(names, ", "))
+			}
+			continue
+		}
+
+		if !f.Exported() && !v.accessible(f, tag) {
+			switch {
+			case ok && f.Pkg() != v.pkg.Types:
+				v.errorf("field %s: unexported field of another package cannot be decoded", f.Name())
+			case ok:
+				v.errorf("field %s: unexported field is tagged, but it is not decoded without the unexported option", f.Name())
+			}
+			continue
+		}
+
+		if ok && !v.parseTag(&field, tag, f.Type()) {
+			continue
+		}
+
+		// embedded struct without a key is promoted like in encoding/json
+		if f.Embedded() && strings.Split(tag, ",")[0] == "" {
+			if fields, ok := v.promote(f, field); ok {
+				for _, p := range fields {
+					promoted = append(promoted, promotedField{StructField: p, pos: f.Pos()})
+					positions[p.Name] = f.Pos()
+				}
+				continue
+			}
 		}
 
-		if f.Tag != nil {
-			tag := reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
-			description[i].Alias = tag.Get("molekula")
+		if field.Rest && bins {
+			v.errorf("field %s: record cannot have a rest field, every field is a bin", field.Name)
+			continue
+		}
+
+		if field.Key && !v.keyField(field, f.Type(), bins, key) {
+			continue
+		}
+		if field.Key {
+			key = field.Name
 		}
+
+		if field.Rest {
+			if rest != "" {
+				v.errorf("field %s: struct already has a rest field %s", field.Name, rest)
+				continue
+			}
+			rest = field.Name
+		}
+
+		field.Type = v.parseType(f.Type())
+		if field.Default != "" && !v.defaultAllowed(field) {
+			continue
+		}
+		if bins {
+			field.TypeName = types.TypeString(f.Type(), v.qualifier)
+		} else if !field.Rest {
+			if name, ok := keys[field.Alias]; ok {
+				v.errorf("field %s: key %q conflicts with field %s", field.Name, field.Alias, name)
+				continue
+			}
+			keys[field.Alias] = field.Name
+		}
+		description = append(description, field)
+		positions[field.Name] = f.Pos()
+	}
+
+	fields := v.merge(description, promoted, rest)
+	if !bins {
+		return fields
+	}
+
+	// own fields shadow promoted ones, so the rest of duplicates are own fields with the same bin
+	record := make(map[string]binOwner, len(fields))
+	valid := fields[:0]
+	for _, field := range fields {
+		bin := field.Alias
+		v.pos, v.currentBinName = positions[field.Name], &bin
+		if v.claimBin(record, bin, binOwner{name: "field " + field.Name, pos: v.pos}) {
+			v.claimFunc("Decode"+v.name+Camel(bin), binOwner{name: "field " + field.Name, pos: v.pos})
+			valid = append(valid, field)
+		}
+	}
+
+	return valid
+}
+
+// keyField reports a key field which isn't a field of record or has a type which can't be a key.
+// The key is the name of key field which is already found in the struct.
+func (v *visitor) keyField(field ast.StructField, t types.Type, bins bool, key string) bool {
+	if !bins {
+		v.errorf("field %s: key option is allowed only for fields of records", field.Name)
+		return false
+	}
+
+	if key != "" {
+		v.errorf("field %s: record already has a key field %s", field.Name, key)
+		return false
+	}
+
+	if b, ok := t.Underlying().(*types.Basic); ok && b.Info()&(types.IsString|types.IsInteger) != 0 || isBlob(t) {
+		return true
+	}
+
+	v.errorf("field %s: key must be a string, an integer or []byte, got %s", field.Name, types.TypeString(t, v.qualifier))
+
+	return false
+}
+
+// accessible returns true if the unexported field can be used by the generated code.
+// Embedded structs of the package are promoted without the unexported option like in encoding/json.
+func (v *visitor) accessible(f *types.Var, tag string) bool {
+	if f.Pkg() != v.pkg.Types {
+		return false
+	}
+
+	if v.unexported {
+		return true
+	}
+
+	_, isStruct := f.Type().Underlying().(*types.Struct)
+
+	return f.Embedded() && isStruct && strings.Split(tag, ",")[0] == ""
+}
+
+// opaque returns true if the struct has fields, but none of them is accessible outside of its package
+// like time.Location. It would be stored as an empty map.
+func opaque(s *types.Struct, pkg *types.Package) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if f := s.Field(i); f.Exported() || f.Pkg() == pkg {
+			return false
+		}
+	}
+
+	return s.NumFields() > 0
+}
+
+// promotedField is a field of embedded struct with a position of embedding
+type promotedField struct {
+	ast.StructField
+	pos token.Pos
+}
+
+// promote returns fields of embedded struct with names relative to the parent struct.
+// It returns false if the field isn't a struct and must be parsed as a regular one.
+func (v *visitor) promote(f *types.Var, field ast.StructField) ([]ast.StructField, bool) {
+	if isTime(f.Type()) {
+		return nil, false
+	}
+
+	if p, ok := f.Type().(*types.Pointer); ok {
+		if _, ok := p.Elem().Underlying().(*types.Struct); ok {
+			v.errorf("field %s: embedded pointer %s cannot be promoted, tag it with a key to nest it", f.Name(), types.TypeString(p, v.qualifier))
+			return nil, true
+		}
+	}
+
+	if _, ok := f.Type().Underlying().(*types.Struct); !ok {
+		return nil, false
+	}
+
+	if field.OmitEmpty || field.Required || field.Default != "" || field.Rest {
+		v.errorf("field %s: options of embedded struct are allowed only with a key", f.Name())
+		return nil, true
+	}
+
+	embedded, ok := v.parseType(f.Type()).(ast.Struct)
+	if !ok {
+		return nil, true
+	}
+
+	fields := make([]ast.StructField, 0, len(embedded.Fields))
+	for _, e := range embedded.Fields {
+		e.Name = f.Name() + "." + e.Name
+		fields = append(fields, e)
+	}
+
+	return fields, true
+}
+
+// merge adds promoted fields to own fields of struct unless own fields have the same keys.
+// Promoted fields of different embedded structs with the same key are reported as conflicting.
+func (v *visitor) merge(fields []ast.StructField, promoted []promotedField, rest string) []ast.StructField {
+	// keys is a mapping of keys to names of fields, the rest field is stored under an empty key
+	keys := make(map[string]string, len(fields))
+	for _, f := range fields {
+		if !f.Rest {
+			keys[f.Alias] = f.Name
+		}
+	}
+	if rest != "" {
+		keys[""] = rest
+	}
+
+	own := make(map[string]bool, len(keys))
+	for key := range keys {
+		own[key] = true
+	}
+
+	for _, p := range promoted {
+		key := p.Alias
+		if p.Rest {
+			key = ""
+		}
+
+		if own[key] {
+			continue
+		}
+
+		if name, ok := keys[key]; ok {
+			v.pos = p.pos
+			if p.Rest {
+				v.errorf("field %s: struct already has a rest field %s", p.Name, name)
+			} else {
+				v.errorf("field %s: key %q conflicts with field %s", p.Name, p.Alias, name)
+			}
+			continue
+		}
+
+		keys[key] = p.Name
+		fields = append(fields, p.StructField)
+	}
+
+	return fields
+}
+
+// parseTag fills the field from a tag like 'name,omitempty,required,default=value'
+func (v *visitor) parseTag(field *ast.StructField, tag string, t types.Type) bool {
+	options := strings.Split(tag, ",")
+	if options[0] != "" {
+		field.Alias = options[0]
+	}
+
+	valid := true
+	for _, option := range options[1:] {
+		switch {
+		case option == "":
+		case option == "omitempty":
+			field.OmitEmpty = true
+		case option == "required":
+			field.Required = true
+		case option == "rest", option == "inline":
+			field.Rest = true
+		case option == "key":
+			field.Key = true
+		case option == "unix", option == "unixnano", option == "rfc3339":
+			if !containsTime(t) {
+				v.errorf("field %s: option %q is allowed only for time.Time", field.Name, option)
+				valid = false
+			}
+			v.timeFormat = option
+		case strings.HasPrefix(option, "default="):
+			literal, err := defaultLiteral(strings.TrimPrefix(option, "default="), t)
+			if err != nil {
+				v.errorf("field %s: %s", field.Name, err)
+				valid = false
+			}
+			field.Default = literal
+		default:
+			v.errorf("field %s: unknown tag option %q", field.Name, option)
+			valid = false
+		}
+	}
+
+	if field.Required && field.Default != "" {
+		v.errorf("field %s: required field cannot have a default value", field.Name)
+		valid = false
+	}
+
+	if field.Required && field.OmitEmpty {
+		v.errorf("field %s: required field cannot be omitempty", field.Name)
+		valid = false
+	}
+
+	if field.Rest {
+		if field.Required || field.Default != "" {
+			v.errorf("field %s: rest field cannot be required or have a default value", field.Name)
+			valid = false
+		}
+
+		if !isRestMap(t) {
+			v.errorf("field %s: rest field must be map[string]interface{}, got %s", field.Name, types.TypeString(t, v.qualifier))
+			valid = false
+		}
+	}
+
+	return valid
+}
+
+// isBlob returns true if the type is a byte slice which is stored as a blob
+func isBlob(t types.Type) bool {
+	s, ok := t.Underlying().(*types.Slice)
+	if !ok {
+		return false
+	}
+
+	b, ok := s.Elem().(*types.Basic)
+
+	return ok && b.Kind() == types.Byte
+}
+
+var (
+	emptyInterface = types.NewInterfaceType(nil, nil).Complete()
+	errorType      = types.Universe.Lookup("error").Type()
+	byteSlice      = types.NewSlice(types.Typ[types.Byte])
+
+	// codecs are pairs of methods which are called instead of decoding and encoding the structure of type
+	binCodec = [2]*types.Func{
+		method("UnmarshalBin", types.NewTuple(param(emptyInterface)), types.NewTuple(param(errorType))),
+		method("MarshalBin", nil, types.NewTuple(param(emptyInterface))),
+	}
+	textCodec = [2]*types.Func{
+		method("UnmarshalText", types.NewTuple(param(byteSlice)), types.NewTuple(param(errorType))),
+		method("MarshalText", nil, types.NewTuple(param(byteSlice), param(errorType))),
+	}
+)
+
+func param(t types.Type) *types.Var {
+	return types.NewParam(token.NoPos, nil, "", t)
+}
+
+func method(name string, params, results *types.Tuple) *types.Func {
+	return types.NewFunc(token.NoPos, nil, name, types.NewSignatureType(nil, nil, nil, params, results, false))
+}
+
+// implements returns true if the method set of pointer to the type has the method.
+// Methods which are generated by molekula in the package are ignored.
+func (v *visitor) implements(t types.Type, m *types.Func) bool {
+	iface := types.NewInterfaceType([]*types.Func{m}, nil).Complete()
+	if !types.Implements(types.NewPointer(t), iface) {
+		return false
+	}
+
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, m.Name())
+
+	return obj != nil && !v.generated[v.pkg.Fset.Position(obj.Pos()).Filename]
+}
+
+// custom returns a description of type which implements a pair of codec methods.
+// It returns false if the type doesn't implement them, a type with only one of bin methods is reported.
+func (v *visitor) custom(n *types.Named, codec [2]*types.Func, text bool) (ast.Type, bool) {
+	decode, encode := v.implements(n, codec[0]), v.implements(n, codec[1])
+	// many types implement only MarshalText for logging, they are decoded as usual
+	if !decode && !encode || text && !(decode && encode) {
+		return nil, false
+	}
+
+	name := types.TypeString(n, v.qualifier)
+	switch {
+	case !encode:
+		return v.errorf("type %s implements %s, but not %s", name, codec[0].Name(), codec[1].Name()), true
+	case !decode:
+		return v.errorf("type %s implements %s, but not %s", name, codec[1].Name(), codec[0].Name()), true
 	}
 
-	return description
+	return ast.Custom{Name: name, Text: text}, true
+}
+
+// isTime returns true if the type is time.Time
+func isTime(t types.Type) bool {
+	n, ok := t.(*types.Named)
+
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time"
 }
 
-func pasrseGoASTType(t goast.Expr) ast.Type {
+// isDuration returns true if the type is time.Duration
+func isDuration(t types.Type) bool {
+	n, ok := t.(*types.Named)
+
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Duration"
+}
+
+// containsTime returns true if the type is time.Time or a pointer, slice, array or map of time.Time values
+func containsTime(t types.Type) bool {
 	switch n := t.(type) {
-	case *goast.Ident:
-		if n.Obj == nil || n.Obj.Decl == nil {
-			return ast.BuiltIn(n.Name)
+	case *types.Pointer:
+		return containsTime(n.Elem())
+	case *types.Slice:
+		return containsTime(n.Elem())
+	case *types.Array:
+		return containsTime(n.Elem())
+	case *types.Map:
+		return containsTime(n.Elem())
+	}
+
+	return isTime(t)
+}
+
+// isRestMap returns true if the type is map[string]interface{} which can hold any unknown key of struct
+func isRestMap(t types.Type) bool {
+	m, ok := t.Underlying().(*types.Map)
+	if !ok {
+		return false
+	}
+
+	key, ok := m.Key().(*types.Basic)
+	if !ok || key.Kind() != types.String {
+		return false
+	}
+
+	value, ok := m.Elem().(*types.Interface)
+
+	return ok && value.Empty()
+}
+
+// defaultAllowed reports a default value of type which isn't stored as its basic type,
+// the default value is decoded as if the bin contains it.
+func (v *visitor) defaultAllowed(field ast.StructField) bool {
+	switch t := field.Type.(type) {
+	case ast.Duration:
+		v.errorf("field %s: default value is not allowed for time.Duration, it's stored as int64", field.Name)
+		return false
+	case ast.Custom:
+		v.errorf("field %s: default value is not allowed for %s, it's decoded by its own methods", field.Name, t.Name)
+		return false
+	}
+
+	return true
+}
+
+// sizes are sizes of basic types on 64-bit platforms which the generated code is built for
+var sizes = types.SizesFor("gc", "amd64")
+
+// defaultLiteral returns a Go literal of the value for a basic type.
+// The value must fit the size of the type, otherwise the generated code doesn't compile.
+func defaultLiteral(value string, t types.Type) (string, error) {
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return "", fmt.Errorf("default value is allowed only for built-in types, got %s", t)
+	}
+
+	// parsed values are formatted again, because parsers accept values like 1 for bool or inf
+	// which aren't Go literals of the type
+	var (
+		literal string
+		err     error
+		bitSize = int(sizes.Sizeof(basic) * 8)
+	)
+	switch info := basic.Info(); {
+	case info&types.IsString != 0:
+		return strconv.Quote(value), nil
+	case info&types.IsBoolean != 0:
+		if value != "true" && value != "false" {
+			err = strconv.ErrSyntax
+		}
+		literal = value
+	case info&types.IsUnsigned != 0:
+		var u uint64
+		u, err = strconv.ParseUint(value, 0, bitSize)
+		literal = strconv.FormatUint(u, 10)
+	case info&types.IsInteger != 0:
+		var i int64
+		i, err = strconv.ParseInt(value, 0, bitSize)
+		literal = strconv.FormatInt(i, 10)
+	case info&types.IsFloat != 0:
+		var f float64
+		f, err = strconv.ParseFloat(value, bitSize)
+		if err == nil && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return "", fmt.Errorf("default value %q is not a finite number", value)
+		}
+		literal = strconv.FormatFloat(f, 'g', -1, bitSize)
+	default:
+		return "", fmt.Errorf("default value is not allowed for %s", t)
+	}
+
+	if errors.Is(err, strconv.ErrRange) {
+		return "", fmt.Errorf("default value %q overflows %s", value, t)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("invalid default value %q for %s", value, t)
+	}
+
+	return literal, nil
+}
+
+// qualifier returns a local name of package for types from other packages and remembers them as imports
+func (v *visitor) qualifier(p *types.Package) string {
+	if p == v.pkg.Types {
+		return ""
+	}
+
+	name, ok := v.importNames[p.Path()]
+	if !ok {
+		name = v.importName(p.Name())
+		v.importNames[p.Path()] = name
+	}
+
+	i := Import{Path: p.Path()}
+	if name != p.Name() {
+		i.Name = name
+	}
+	v.imports[p.Path()] = i
+
+	return name
+}
+
+// importName returns a name which isn't used by other imports and declarations of the package:
+// the package name or the name with a number like models2
+func (v *visitor) importName(pkgName string) string {
+	used := func(name string) bool {
+		// the runtime and client packages are always imported under these names
+		if name == "molekula" || name == "aerospike" || v.pkg.Types.Scope().Lookup(name) != nil {
+			return true
+		}
+
+		for _, n := range v.importNames {
+			if n == name {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	name := pkgName
+	for i := 2; used(name); i++ {
+		name = pkgName + strconv.Itoa(i)
+	}
+
+	return name
+}
+
+// errorf reports a diagnostic at the position of current declaration
+func (v *visitor) errorf(format string, args ...interface{}) ast.Type {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Pos:     v.pkg.Fset.Position(v.pos),
+		BinName: *v.currentBinName,
+		Message: fmt.Sprintf(format, args...),
+	})
+
+	return nil
+}
+
+func (v *visitor) parseType(t types.Type) ast.Type {
+	switch n := types.Unalias(t).(type) {
+	case *types.Basic:
+		switch {
+		case n.Kind() == types.Invalid:
+			return v.errorf("type is invalid or undefined")
+		case n.Info()&types.IsComplex != 0, n.Kind() == types.Uintptr, n.Kind() == types.UnsafePointer:
+			return v.errorf("%s cannot be stored in a bin", n.Name())
+		}
+
+		return ast.BuiltIn(n.Name())
+	case *types.Named:
+		if custom, ok := v.custom(n, binCodec, false); ok {
+			return custom
+		}
+
+		switch {
+		case isTime(n):
+			v.imports["time"] = Import{Path: "time"}
+			if v.timeFormat == "" {
+				return ast.Time{Format: "unixnano"}
+			}
+
+			return ast.Time{Format: v.timeFormat}
+		case isDuration(n):
+			v.imports["time"] = Import{Path: "time"}
+
+			return ast.Duration{}
+		}
+
+		if isBlob(n) {
+			return ast.Blob(types.TypeString(n, v.qualifier))
 		}
 
-		typeSpec, ok := n.Obj.Decl.(*goast.TypeSpec)
+		if custom, ok := v.custom(n, textCodec, true); ok {
+			return custom
+		}
+
+		if b, ok := n.Underlying().(*types.Basic); ok {
+			return v.named(n, b)
+		}
+
+		s, ok := n.Underlying().(*types.Struct)
 		if !ok {
-			return nil
+			return v.parseType(n.Underlying())
 		}
 
+		if opaque(s, v.pkg.Types) {
+			return v.errorf("struct %s has only unexported fields of another package, they cannot be decoded", types.TypeString(n, v.qualifier))
+		}
+
+		if v.visiting[n] {
+			return v.errorf("recursive type %s is not supported", types.TypeString(n, v.qualifier))
+		}
+		v.visiting[n] = true
+		defer delete(v.visiting, n)
+
+		return ast.Struct{
+			Name:   types.TypeString(n, v.qualifier),
+			Fields: v.parseStruct(s),
+		}
+	case *types.Struct:
 		return ast.Struct{
-			Name:   typeSpec.Name.Name,
-			Fields: parseStruct(typeSpec.Type.(*goast.StructType)),
+			Name:   types.TypeString(n, v.qualifier),
+			Fields: v.parseStruct(n),
+		}
+	case *types.Pointer:
+		return ast.Pointer{
+			Element: v.parseType(n.Elem()),
+		}
+	case *types.Interface:
+		if n.Empty() {
+			return ast.BuiltIn("interface{}")
+		}
+
+		return v.errorf("non-empty interface %s cannot be decoded", types.TypeString(n, v.qualifier))
+	case *types.Slice:
+		if isBlob(n) {
+			return ast.Blob("[]byte")
 		}
-	case *goast.InterfaceType:
-		return ast.BuiltIn("interface{}")
-	case *goast.ArrayType:
+
 		return ast.Array{
-			Element: pasrseGoASTType(n.Elt),
+			Element: v.parseType(n.Elem()),
+		}
+	case *types.Array:
+		return ast.FixedArray{
+			Element: v.parseType(n.Elem()),
+			Len:     n.Len(),
 		}
-	case *goast.MapType:
+	case *types.Map:
+		b, ok := n.Key().Underlying().(*types.Basic)
+		if !ok {
+			return v.errorf("map key type %s is not supported, only built-in types are allowed", types.TypeString(n.Key(), v.qualifier))
+		}
+
+		// keys are always decoded as built-in types, even if the type has codec methods
+		key := v.parseType(b)
+		if named, ok := types.Unalias(n.Key()).(*types.Named); ok {
+			key = v.named(named, b)
+		}
+
 		return ast.Map{
-			Key:   ast.BuiltIn(n.Key.(*goast.Ident).Name),
-			Value: pasrseGoASTType(n.Value),
+			Key:   key,
+			Value: v.parseType(n.Elem()),
 		}
+	case *types.Chan:
+		return v.errorf("channel types cannot be stored in a bin")
+	case *types.Signature:
+		return v.errorf("function types cannot be stored in a bin")
 	}
-	return nil
+
+	return v.errorf("type %s is not supported", types.TypeString(t, v.qualifier))
+}
+
+// object adds an Object for declared type if the type can be stored in a bin
+func (v *visitor) object(node *goast.TypeSpec) {
+	v.imports = make(map[string]Import)
+	v.visiting = make(map[*types.Named]bool)
+	v.pos = node.Pos()
+
+	def := v.pkg.TypesInfo.Defs[node.Name]
+	if def == nil {
+		v.errorf("type %s has no type information", node.Name.Name)
+		return
+	}
+
+	diagnostics := len(v.diagnostics)
+
+	o := Object{
+		Name:      node.Name.Name,
+		Directive: v.directive,
+		Methods:   hasMethods(def),
+	}
+
+	// errors of a record belong to its fields, each of them is a separate bin
+	if o.Record {
+		o.BinName = ""
+		v.currentBinName = &o.BinName
+	}
+
+	for _, err := range v.directiveErrors {
+		v.errorf("%s", err)
+	}
+
+	if !o.Record && v.claimBin(v.set(o.Set), o.BinName, binOwner{name: "type " + o.Name, pos: v.pos}) {
+		decoder := o.Func
+		if decoder == "" {
+			decoder = "Decode" + o.Name + Camel(o.BinName)
+		}
+		v.claimFunc(decoder, binOwner{name: "type " + o.Name, pos: v.pos})
+	}
+
+	v.unexported, v.name = o.Unexported, o.Name
+	if o.Record {
+		if _, ok := def.Type().Underlying().(*types.Struct); !ok {
+			v.errorf("record %s must be a struct, its fields are bins", o.Name)
+			return
+		}
+		v.bins = true
+	}
+	o.Type = v.parseType(def.Type())
+	v.bins = false
+
+	if s, ok := o.Type.(ast.Struct); ok && o.Record && o.Namespace == "" {
+		for _, f := range s.Fields {
+			if f.Key {
+				v.errorf("record %s has a key field %s, but no ns option", o.Name, f.Name)
+			}
+		}
+	}
+
+	if len(v.diagnostics) > diagnostics {
+		return
+	}
+
+	for _, i := range v.imports {
+		o.Imports = append(o.Imports, i)
+	}
+	sort.Slice(o.Imports, func(i, j int) bool { return o.Imports[i].Path < o.Imports[j].Path })
+
+	v.objects = append(v.objects, o)
+}
+
+// hasMethods returns true if methods can be declared on the defined type
+func hasMethods(def types.Object) bool {
+	if t, ok := def.(*types.TypeName); !ok || t.IsAlias() {
+		return false
+	}
+
+	switch def.Type().Underlying().(type) {
+	case *types.Pointer, *types.Interface:
+		return false
+	}
+
+	return true
 }
 
+// Visit parses type declarations of a file, a directive is applied only to the type which it annotates.
+// Types declared inside functions are skipped because the generated code can't refer to them.
 func (v *visitor) Visit(n goast.Node) goast.Visitor {
 	switch node := n.(type) {
 	case *goast.GenDecl:
-		binName, ok := parseBinName(node)
-		if ok {
-			v.currentBinName = &binName
-		}
-	case *goast.TypeSpec:
-		if v.currentBinName == nil {
-			break
-		}
-		switch t := node.Type.(type) {
-		case *goast.StructType:
-			v.objects = append(v.objects, Object{
-				BinName: *v.currentBinName,
-				Type: ast.Struct{
-					Name:   node.Name.Name,
-					Fields: parseStruct(t),
-				},
-			})
-			v.currentBinName = nil
-		case *goast.MapType, *goast.ArrayType, *goast.Ident:
-			v.objects = append(v.objects, Object{
-				Type:    pasrseGoASTType(t),
-				BinName: *v.currentBinName,
-			})
+		if node.Tok != token.TYPE {
+			return nil
 		}
+
+		// a comment of grouped declaration belongs to the group, not to any of its types
+		if directive, ok := parseDirective(node.Doc); ok && node.Lparen.IsValid() {
+			v.setDirective(directive)
+			v.pos = node.Pos()
+			v.errorf("directive of grouped declaration is ambiguous, annotate a type inside the group")
+		}
+
+		for _, spec := range node.Specs {
+			typeSpec := spec.(*goast.TypeSpec)
+
+			// enums are collected before parsing, they don't have own codecs, only their options are checked
+			if directive, ok := parseDirective(specDoc(node, typeSpec)); ok {
+				if v.setDirective(directive); !v.directive.Enum {
+					v.object(typeSpec)
+					continue
+				}
+
+				v.pos = typeSpec.Pos()
+				for _, err := range v.directiveErrors {
+					v.errorf("%s", err)
+				}
+			}
+		}
+
+		v.currentBinName = nil
+		v.directive, v.directiveErrors = Directive{}, nil
+
+		return nil
+	case *goast.FuncDecl:
+		return nil
 	}
 
 	return v
 }
+
+// set returns bins which are claimed by types of the set
+func (v *visitor) set(name string) map[string]binOwner {
+	bins, ok := v.sets[name]
+	if !ok {
+		bins = make(map[string]binOwner)
+		v.sets[name] = bins
+	}
+
+	return bins
+}
+
+// specDoc returns a comment of the type spec which may contain a directive.
+// A comment of single declaration belongs to the declaration, not to the spec.
+func specDoc(decl *goast.GenDecl, spec *goast.TypeSpec) *goast.CommentGroup {
+	if !decl.Lparen.IsValid() {
+		return decl.Doc
+	}
+
+	return spec.Doc
+}
+
+// setDirective sets the directive of the next parsed type from a text like bin=name option=value
+func (v *visitor) setDirective(directive string) {
+	v.directive, v.directiveErrors = newDirective(directive)
+	v.currentBinName = &v.directive.BinName
+}
@@ -1,29 +1,25 @@
 package parser
 
 import (
-	goparser "go/parser"
-	"go/token"
+	"path/filepath"
 	"testing"
 
 	"github.com/nikgalushko/molekula/internal/ast"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParser_Parse(t *testing.T) {
-	fset := token.NewFileSet()
+	pkg, err := Load("testdata/")
+	require.NoError(t, err)
 
-	pkgs, err := goparser.ParseDir(fset, "testdata/", nil, goparser.ParseComments)
-	assert.NoError(t, err)
-
-	var objects []Object
-	for _, pkg := range pkgs {
-		for _, f := range pkg.Files {
-			objects = Parse(f)
-		}
-	}
+	objects, diagnostics := Parse(pkg)
+	assert.Empty(t, diagnostics)
 
 	assert.Equal(t, Object{
-		BinName: "data",
+		Directive: Directive{BinName: "data"},
+		Name:      "Bar",
+		Methods:   true,
 		Type: ast.Map{
 			Key: ast.BuiltIn("string"),
 			Value: ast.Map{
@@ -34,7 +30,9 @@ func TestParser_Parse(t *testing.T) {
 	}, find(objects, "data"))
 
 	assert.Equal(t, Object{
-		BinName: "kek",
+		Directive: Directive{BinName: "kek"},
+		Name:      "Foo",
+		Methods:   true,
 		Type: ast.Struct{
 			Name: "Foo",
 			Fields: []ast.StructField{
@@ -68,17 +66,23 @@ func TestParser_Parse(t *testing.T) {
 	}, find(objects, "kek"))
 
 	assert.Equal(t, Object{
-		BinName: "config_version",
-		Type:    ast.BuiltIn("int"),
+		Directive: Directive{BinName: "config_version"},
+		Name:      "Version",
+		Methods:   true,
+		Type:      ast.Named{Name: "Version", Underlying: ast.BuiltIn("int")},
 	}, find(objects, "config_version"))
 
 	assert.Equal(t, Object{
-		BinName: "weights",
-		Type:    ast.Array{Element: ast.BuiltIn("float64")},
+		Directive: Directive{BinName: "weights", StrictNumeric: true},
+		Name:      "Weights",
+		Methods:   true,
+		Type:      ast.Array{Element: ast.BuiltIn("float64")},
 	}, find(objects, "weights"))
 
 	assert.Equal(t, Object{
-		BinName: "config",
+		Directive: Directive{BinName: "config"},
+		Name:      "Config",
+		Methods:   true,
 		Type: ast.Map{
 			Key: ast.BuiltIn("string"),
 			Value: ast.Struct{
@@ -92,7 +96,9 @@ func TestParser_Parse(t *testing.T) {
 	}, find(objects, "config"))
 
 	assert.Equal(t, Object{
-		BinName: "slice",
+		Directive: Directive{BinName: "slice"},
+		Name:      "Slice",
+		Methods:   true,
 		Type: ast.Array{
 			Element: ast.Struct{
 				Name: "Value",
@@ -103,6 +109,425 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 	}, find(objects, "slice"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "optional", Strict: true},
+		Name:      "Optional",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Optional",
+			Fields: []ast.StructField{
+				{Name: "Name", Alias: "name", Type: ast.Pointer{Element: ast.BuiltIn("string")}},
+				{
+					Name:  "Values",
+					Alias: "values",
+					Type: ast.Array{
+						Element: ast.Pointer{
+							Element: ast.Struct{
+								Name: "Value",
+								Fields: []ast.StructField{
+									{Name: "Gender", Alias: "gender", Type: ast.BuiltIn("string")},
+									{Name: "ID", Alias: "id", Type: ast.BuiltIn("int64")},
+								},
+							},
+						},
+					},
+				},
+				{
+					Name:  "Counters",
+					Alias: "counters",
+					Type: ast.Map{
+						Key:   ast.BuiltIn("string"),
+						Value: ast.Pointer{Element: ast.BuiltIn("int")},
+					},
+				},
+			},
+		},
+	}, find(objects, "optional"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "users"},
+		Name:      "Users",
+		Methods:   true,
+		Type: ast.Array{
+			Element: ast.Struct{
+				Name: "models.User",
+				Fields: []ast.StructField{
+					{Name: "Name", Alias: "n", Type: ast.BuiltIn("string")},
+					{Name: "Age", Alias: "age", Type: ast.BuiltIn("int")},
+				},
+			},
+		},
+		Imports: []Import{{Path: "github.com/nikgalushko/molekula/internal/parser/testdata/models"}},
+	}, find(objects, "users"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "tagged", RejectUnknown: true},
+		Name:      "Tagged",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Tagged",
+			Fields: []ast.StructField{
+				{Name: "Name", Alias: "n", Type: ast.BuiltIn("string"), OmitEmpty: true},
+				{Name: "Dash", Alias: "-", Type: ast.BuiltIn("int")},
+				{Name: "ID", Alias: "id", Type: ast.BuiltIn("int64"), Required: true},
+				{Name: "Limit", Alias: "limit", Type: ast.BuiltIn("uint"), Default: "10"},
+				{Name: "Title", Alias: "title", Type: ast.BuiltIn("string"), Default: `"none"`},
+				{Name: "Ratio", Alias: "ratio", Type: ast.BuiltIn("float32"), Default: "0.25"},
+				{Name: "Mask", Alias: "mask", Type: ast.BuiltIn("int"), Default: "15"},
+			},
+		},
+	}, find(objects, "tagged"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "profile", Set: "users", Func: "DecodeProfile", Strict: true},
+		Name:      "Profile",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Profile",
+			Fields: []ast.StructField{
+				{Name: "Name", Alias: "name", Type: ast.BuiltIn("string")},
+				{
+					Name:  "Extra",
+					Alias: "extra",
+					Type:  ast.Map{Key: ast.BuiltIn("string"), Value: ast.BuiltIn("interface{}")},
+					Rest:  true,
+				},
+			},
+		},
+	}, find(objects, "profile"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "account"},
+		Name:      "Account",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Account",
+			Fields: []ast.StructField{
+				{
+					Name:  "Meta",
+					Alias: "meta",
+					Type: ast.Struct{
+						Name:   "Meta",
+						Fields: []ast.StructField{{Name: "Version", Alias: "version", Type: ast.BuiltIn("int")}},
+					},
+				},
+				{Name: "Name", Alias: "name", Type: ast.BuiltIn("string")},
+				{Name: "ID", Alias: "id", Type: ast.BuiltIn("int")},
+				{Name: "Audit.Created", Alias: "created", Type: ast.BuiltIn("int64")},
+			},
+		},
+		Imports: []Import{{Path: "github.com/nikgalushko/molekula/internal/parser/testdata/models"}},
+	}, find(objects, "account"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "point", Unexported: true},
+		Name:      "Point",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Point",
+			Fields: []ast.StructField{
+				{Name: "X", Alias: "x", Type: ast.BuiltIn("int")},
+				{Name: "Y", Alias: "y", Type: ast.BuiltIn("int")},
+				{Name: "label", Alias: "label", Type: ast.BuiltIn("string")},
+			},
+		},
+	}, find(objects, "point"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "person"},
+		Name:      "Person",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Person",
+			Fields: []ast.StructField{
+				{Name: "Name", Alias: "name", Type: ast.BuiltIn("string")},
+				{Name: "audit.Updated", Alias: "updated", Type: ast.BuiltIn("int64")},
+			},
+		},
+	}, find(objects, "person"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "first"},
+		Name:      "First",
+		Methods:   true,
+		Type:      ast.Named{Name: "First", Underlying: ast.BuiltIn("int")},
+	}, find(objects, "first"))
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "third", StrictNumeric: true},
+		Name:      "Third",
+		Methods:   true,
+		Type:      ast.Array{Element: ast.BuiltIn("string")},
+	}, find(objects, "third"))
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "level"},
+		Name:      "Level",
+		Methods:   true,
+		Type:      ast.Named{Name: "Level", Underlying: ast.BuiltIn("int")},
+	}, find(objects, "level"))
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "position"},
+		Name:      "Position",
+		Methods:   true,
+		Type:      ast.FixedArray{Element: ast.BuiltIn("float64"), Len: 3},
+	}, find(objects, "position"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "file"},
+		Name:      "File",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "File",
+			Fields: []ast.StructField{
+				{Name: "Name", Alias: "name", Type: ast.BuiltIn("string")},
+				{Name: "Data", Alias: "data", Type: ast.Blob("[]byte")},
+				{Name: "Hash", Alias: "hash", Type: ast.Blob("Hash")},
+				{Name: "Chunks", Alias: "chunks", Type: ast.Array{Element: ast.Blob("[]byte")}},
+			},
+		},
+	}, find(objects, "file"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "event"},
+		Name:      "Event",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Event",
+			Fields: []ast.StructField{
+				{Name: "At", Alias: "at", Type: ast.Time{Format: "unixnano"}},
+				{Name: "Created", Alias: "created", Type: ast.Pointer{Element: ast.Time{Format: "unix"}}},
+				{Name: "History", Alias: "history", Type: ast.Array{Element: ast.Time{Format: "rfc3339"}}},
+				{Name: "TTL", Alias: "ttl", Type: ast.Duration{}},
+			},
+		},
+		Imports: []Import{{Path: "time"}},
+	}, find(objects, "event"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "payment"},
+		Name:      "Payment",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Payment",
+			Fields: []ast.StructField{
+				{Name: "Amount", Alias: "amount", Type: ast.Custom{Name: "Money"}},
+				{Name: "Codes", Alias: "codes", Type: ast.Array{Element: ast.Custom{Name: "Code", Text: true}}},
+			},
+		},
+	}, find(objects, "payment"))
+
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "membership"},
+		Name:      "Membership",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Membership",
+			Fields: []ast.StructField{
+				{
+					Name:  "Status",
+					Alias: "status",
+					Type: ast.Named{
+						Name:       "models.Status",
+						Underlying: ast.BuiltIn("string"),
+						Enum:       []string{`"active"`, `"blocked"`},
+					},
+				},
+				{
+					Name:  "Levels",
+					Alias: "levels",
+					Type: ast.Map{
+						Key:   ast.Named{Name: "Level", Underlying: ast.BuiltIn("int")},
+						Value: ast.Array{Element: ast.Named{Name: "Version", Underlying: ast.BuiltIn("int")}},
+					},
+				},
+			},
+		},
+		Imports: []Import{{Path: "github.com/nikgalushko/molekula/internal/parser/testdata/models"}},
+	}, find(objects, "membership"))
+
+	var record Object
+	for _, o := range objects {
+		if o.Record {
+			record = o
+		}
+	}
+	assert.Equal(t, Object{
+		Directive: Directive{Record: true, Set: "customers", Namespace: "prod", Repo: true, Strict: true},
+		Name:      "Customer",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Customer",
+			Fields: []ast.StructField{
+				{Name: "ID", Alias: "id", Type: ast.BuiltIn("int64"), TypeName: "int64", Key: true},
+				{Name: "Name", Alias: "name", Type: ast.BuiltIn("string"), TypeName: "string", Required: true},
+				{Name: "Level", Alias: "lvl", Type: ast.Named{Name: "Level", Underlying: ast.BuiltIn("int")}, TypeName: "Level", Default: "1"},
+				{Name: "Tags", Alias: "tags", Type: ast.Array{Element: ast.BuiltIn("string")}, TypeName: "[]string", OmitEmpty: true},
+				{
+					Name:     "Profile",
+					Alias:    "profile",
+					TypeName: "Profile",
+					Type: ast.Struct{
+						Name: "Profile",
+						Fields: []ast.StructField{
+							{Name: "Name", Alias: "name", Type: ast.BuiltIn("string")},
+							{Name: "Extra", Alias: "extra", Type: ast.Map{Key: ast.BuiltIn("string"), Value: ast.BuiltIn("interface{}")}, Rest: true},
+						},
+					},
+				},
+				{Name: "Meta.Version", Alias: "version", Type: ast.BuiltIn("int")},
+			},
+		},
+	}, record)
+
+	for _, o := range objects {
+		assert.NotContains(t, []string{"Second", "Unannotated", "ArrayType", "Meta", "audit"}, o.Name, "directive of %s is applied to another type", o.BinName)
+	}
+}
+
+func TestParser_ParseDiagnostics(t *testing.T) {
+	pkg, err := Load("testdata/invalid/")
+	require.NoError(t, err)
+
+	objects, diagnostics := Parse(pkg)
+	assert.Equal(t, []Object{{
+		Directive: Directive{BinName: "valid"},
+		Name:      "Valid",
+		Methods:   true,
+		Type: ast.Struct{
+			Name:   "Valid",
+			Fields: []ast.StructField{{Name: "Name", Alias: "name", Type: ast.BuiltIn("string")}},
+		},
+	}, {
+		Directive: Directive{BinName: "dup"},
+		Name:      "DupA",
+		Methods:   true,
+		Type:      ast.Named{Name: "DupA", Underlying: ast.BuiltIn("int")},
+	}, {
+		Directive: Directive{BinName: "shared", Set: "a"},
+		Name:      "SharedA",
+		Methods:   true,
+		Type:      ast.Named{Name: "SharedA", Underlying: ast.BuiltIn("int")},
+	}, {
+		Directive: Directive{BinName: "shared", Set: "b"},
+		Name:      "SharedB",
+		Methods:   true,
+		Type:      ast.Named{Name: "SharedB", Underlying: ast.BuiltIn("int")},
+	}}, objects)
+
+	messages := make([]string, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		d.Pos.Filename = filepath.Base(d.Pos.Filename)
+		messages = append(messages, d.Error())
+	}
+
+	assert.Equal(t, []string{
+		`defaults.go:7:2: bin "timeouts": field TTL: default value is not allowed for time.Duration, it's stored as int64`,
+		`defaults.go:24:2: bin "prices": field Cur: default value is not allowed for Currency, it's decoded by its own methods`,
+		`user.go:24:2: bin "login": field token: unexported field of another package cannot be decoded`,
+		`invalid.go:6:6: bin "events": channel types cannot be stored in a bin`,
+		`invalid.go:9:6: bin "handlers": function types cannot be stored in a bin`,
+		`invalid.go:14:2: bin "entry": map key type token.Position is not supported, only built-in types are allowed`,
+		`invalid.go:15:2: bin "entry": non-empty interface interface{String() string} cannot be decoded`,
+		`invalid.go:16:2: bin "entry": complex128 cannot be stored in a bin`,
+		`invalid.go:21:2: bin "tree": recursive type Tree is not supported`,
+		`invalid.go:30:6: bin "options": unknown option "numeric=fast"`,
+		`invalid.go:34:2: bin "tags": field Number: invalid default value "abc" for int`,
+		`invalid.go:35:2: bin "tags": field Option: unknown tag option "optional"`,
+		`invalid.go:36:2: bin "tags": field Both: required field cannot have a default value`,
+		`invalid.go:37:2: bin "tags": field Numbers: default value is allowed only for built-in types, got []int`,
+		`invalid.go:42:6: bin "rest": unknown option "unknown=keep"`,
+		`invalid.go:43:2: bin "rest": field Extra: rest field must be map[string]interface{}, got map[string]int`,
+		`invalid.go:45:2: bin "rest": field Second: struct already has a rest field Other`,
+		`invalid.go:46:2: bin "rest": field Third: rest field cannot be required or have a default value`,
+		`invalid.go:51:3: bin "embedded": field Valid: embedded pointer *Valid cannot be promoted, tag it with a key to nest it`,
+		`invalid.go:54:2: bin "embedded": field TaggedBase: options of embedded struct are allowed only with a key`,
+		`invalid.go:53:2: bin "embedded": field Other.Title: key "name" conflicts with field Base.Name`,
+		`invalid.go:71:2: bin "pair": field A: tag is shared by fields A, B, declare them separately`,
+		`invalid.go:72:2: bin "pair": field secret: unexported field is tagged, but it is not decoded without the unexported option`,
+		`invalid.go:76:1: bin "group": directive of grouped declaration is ambiguous, annotate a type inside the group`,
+		`invalid.go:82:2: bin "schedule": field Every: option "unix" is allowed only for time.Time`,
+		`invalid.go:87:2: bin "half": type Decoder implements UnmarshalBin, but not MarshalBin`,
+		`invalid.go:106:2: bin "palette": enum Ratio must be a string or an integer type`,
+		`invalid.go:107:2: bin "palette": enum Color has no declared constants`,
+		`invalid.go:111:6: record Bins must be a struct, its fields are bins`,
+		`invalid.go:116:2: bin "extra": field Extra: record cannot have a rest field, every field is a bin`,
+		`invalid.go:117:2: bin "bad": complex64 cannot be stored in a bin`,
+		`invalid.go:121:6: bin name is empty`,
+		`invalid.go:124:6: bin "much_too_long_name": bin name is 18 bytes long, aerospike allows at most 15`,
+		`invalid.go:127:6: bin "bad.name": bin name contains '.', only ASCII letters, digits, '_' and '-' are allowed`,
+		`invalid.go:133:6: bin "dup": bin name is already used by type DupA at invalid.go:130`,
+		`invalid.go:138:2: bin "x": bin name is already used by field A at invalid.go:137`,
+		`invalid.go:139:2: bin "größe": bin name contains 'ö', only ASCII letters, digits, '_' and '-' are allowed`,
+		`invalid.go:143:6: bin "odd": option "bin" is repeated`,
+		`invalid.go:146:6: bin "fn": option "set" has no value`,
+		`invalid.go:146:6: bin "fn": func "profile" must be an identifier which starts with Decode`,
+		`invalid.go:149:6: option "bin" is not allowed for records, their fields are bins`,
+		`invalid.go:160:6: bin "located": option "ns" is allowed only for records`,
+		`invalid.go:164:2: bin "ratio": field Ratio: key must be a string, an integer or []byte, got float64`,
+		`invalid.go:166:2: bin "other": field Other: record already has a key field ID`,
+		`invalid.go:169:3: bin "inner": field ID: key option is allowed only for fields of records`,
+		`invalid.go:163:6: record Keys has a key field ID, but no ns option`,
+		`invalid.go:175:2: bin "optional": field Name: required field cannot be omitempty`,
+		`invalid.go:180:2: bin "overflow": field Small: default value "300" overflows int8`,
+		`invalid.go:181:2: bin "overflow": field Tiny: default value "1e300" overflows float32`,
+		`invalid.go:187:2: bin "opaque": struct token.FileSet has only unexported fields of another package, they cannot be decoded`,
+		`invalid.go:188:2: bin "opaque": struct FileSet has only unexported fields of another package, they cannot be decoded`,
+		`invalid.go:196:2: bin "aB": decoder DecodeCamelAB is already generated for field AB at invalid.go:195`,
+		`invalid.go:200:6: bin "camel": decoder DecodeCamelAB is already generated for field AB at invalid.go:195`,
+		`invalid.go:204:2: bin "literals": field On: invalid default value "1" for bool`,
+		`invalid.go:205:2: bin "literals": field Limit: default value "inf" is not a finite number`,
+		`invalid.go:209:6: bin "level": option "bin" is not allowed for enums, they don't have codecs`,
+		`invalid.go:220:2: bin "couple": field B: key "x" conflicts with field A`,
+	}, messages)
+}
+
+func TestParser_Imports(t *testing.T) {
+	pkg, err := Load("testdata/")
+	require.NoError(t, err)
+
+	objects, diagnostics := Parse(pkg)
+	assert.Empty(t, diagnostics)
+
+	// packages with the same name get unique local names
+	assert.Equal(t, Object{
+		Directive: Directive{BinName: "migration"},
+		Name:      "Migration",
+		Methods:   true,
+		Type: ast.Struct{
+			Name: "Migration",
+			Fields: []ast.StructField{
+				{Name: "From", Alias: "from", Type: ast.Struct{
+					Name:   "models2.User",
+					Fields: []ast.StructField{{Name: "Login", Alias: "login", Type: ast.BuiltIn("string")}},
+				}},
+				{Name: "To", Alias: "to", Type: ast.Struct{
+					Name: "models.User",
+					Fields: []ast.StructField{
+						{Name: "Name", Alias: "n", Type: ast.BuiltIn("string")},
+						{Name: "Age", Alias: "age", Type: ast.BuiltIn("int")},
+					},
+				}},
+			},
+		},
+		Imports: []Import{
+			{Path: "github.com/nikgalushko/molekula/internal/parser/testdata/legacy/models", Name: "models2"},
+			{Path: "github.com/nikgalushko/molekula/internal/parser/testdata/models"},
+		},
+	}, find(objects, "migration"))
+}
+
+func TestParser_Methods(t *testing.T) {
+	pkg, err := Load("testdata/")
+	require.NoError(t, err)
+
+	objects, diagnostics := Parse(pkg)
+	assert.Empty(t, diagnostics)
+
+	// methods can't be declared on pointers, interfaces and aliases
+	assert.True(t, find(objects, "data").Methods)
+	assert.False(t, find(objects, "counter").Methods)
+	assert.False(t, find(objects, "anything").Methods)
+	assert.False(t, find(objects, "scores").Methods)
 }
 
 func find(objects []Object, name string) Object {
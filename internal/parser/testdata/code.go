@@ -3,6 +3,10 @@ package testdata
 import (
 	"fmt"
 	"go/token"
+	"time"
+
+	legacy "github.com/nikgalushko/molekula/internal/parser/testdata/legacy/models"
+	"github.com/nikgalushko/molekula/internal/parser/testdata/models"
 )
 
 const (
@@ -12,12 +16,13 @@ const (
 )
 
 /*
-	molekula:data
+molekula:data
 */
 type Bar map[string]map[string]int
 
-//molekula:kek
 // Foo is not a Bar
+//
+//molekula:kek
 type Foo struct {
 	Str    string `molekula:"version"`
 	Intrf  interface{}
@@ -29,7 +34,6 @@ type Foo struct {
 // A type is represented by a tree consisting of one
 // or more of the following type-specific expression
 // nodes.
-//
 type (
 	// An ArrayType node represents an array or slice type.
 	ArrayType struct {
@@ -84,7 +88,7 @@ func (v Version) IsProd() bool {
 	return v == 5
 }
 
-//molekula:weights
+//molekula:weights,numeric=strict
 type Weights []float64
 
 func (w Weights) Max() float64 {
@@ -103,11 +107,6 @@ func CallFoo() {
 	fmt.Println("foo called", f)
 }
 
-type Value struct {
-	Gender string
-	ID     int64
-}
-
 //molekula:config
 type Config map[string]Value
 
@@ -116,3 +115,164 @@ type Config2 map[string]int
 
 //molekula:slice
 type Slice []Value
+
+//molekula:optional,strict
+type Optional struct {
+	Name     *string
+	Values   []*Value
+	Counters map[string]*int
+}
+
+//molekula:users
+type Users []models.User
+
+//molekula:bin=tagged unknown=reject
+type Tagged struct {
+	Name    string  `molekula:"n,omitempty"`
+	Skipped int     `molekula:"-"`
+	Dash    int     `molekula:"-,"`
+	ID      int64   `json:"id" molekula:",required"`
+	Limit   uint    `molekula:"limit,default=10"`
+	Title   string  `molekula:",default=none"`
+	Ratio   float32 `molekula:"ratio,default=.25"`
+	Mask    int     `molekula:"mask,default=0x0f"`
+}
+
+//molekula:bin=profile set=users strict numeric=widen func=DecodeProfile
+type Profile struct {
+	Name  string
+	Extra map[string]interface{} `molekula:",rest"`
+}
+
+//molekula:account
+type Account struct {
+	models.Audit
+	Meta `molekula:"meta"`
+	Name string
+	ID   int
+}
+
+type Meta struct {
+	Version int
+}
+
+//molekula:point,unexported
+type Point struct {
+	X, Y   int
+	label  string
+	hidden bool `molekula:"-"`
+}
+
+//molekula:person
+type Person struct {
+	audit
+	Name string
+	age  int
+}
+
+type audit struct {
+	Updated int64
+	by      string
+}
+
+type (
+	//molekula:first
+	First int
+
+	Second map[string]int
+
+	// Third is annotated inside the group
+	//molekula:third,numeric=strict
+	Third []string
+)
+
+//molekula:level
+type Level int
+
+type Unannotated map[string]int
+
+//molekula:position
+type Position [3]float64
+
+//molekula:file
+type File struct {
+	Name   string
+	Data   []byte
+	Hash   Hash
+	Chunks [][]byte
+}
+
+type Hash []byte
+
+//molekula:event
+type Event struct {
+	At      time.Time
+	Created *time.Time  `molekula:",unix"`
+	History []time.Time `molekula:"history,rfc3339"`
+	TTL     time.Duration
+}
+
+//molekula:payment
+type Payment struct {
+	Amount Money
+	Codes  []Code
+}
+
+// Money is an amount of cents
+type Money int64
+
+func (m *Money) UnmarshalBin(data interface{}) error {
+	cents, ok := data.(int)
+	*m = Money(cents)
+	if !ok {
+		return fmt.Errorf("invalid money %v", data)
+	}
+
+	return nil
+}
+
+func (m Money) MarshalBin() interface{} {
+	return int(m)
+}
+
+type Code string
+
+func (c *Code) UnmarshalText(text []byte) error {
+	*c = Code(text)
+	return nil
+}
+
+func (c Code) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+//molekula:membership
+type Membership struct {
+	Status models.Status
+	Levels map[Level][]Version
+}
+
+//molekula:record ns=prod set=customers strict repo
+type Customer struct {
+	ID      int64    `molekula:"id,key"`
+	Name    string   `molekula:",required"`
+	Level   Level    `molekula:"lvl,default=1"`
+	Tags    []string `molekula:",omitempty"`
+	Profile Profile
+	Meta
+}
+
+//molekula:counter
+type Counter *int
+
+//molekula:anything
+type Anything interface{}
+
+//molekula:scores
+type Scores = map[string]int
+
+//molekula:migration
+type Migration struct {
+	From legacy.User
+	To   models.User
+}
@@ -0,0 +1,25 @@
+package invalid
+
+import "time"
+
+//molekula:bin=timeouts
+type Timeouts struct {
+	TTL time.Duration `molekula:"ttl,default=1000"`
+}
+
+// Currency is stored as a text
+type Currency string
+
+func (c *Currency) UnmarshalText(text []byte) error {
+	*c = Currency(text)
+	return nil
+}
+
+func (c Currency) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+//molekula:bin=prices
+type Prices struct {
+	Cur Currency `molekula:"cur,default=usd"`
+}
@@ -0,0 +1,8 @@
+package invalid
+
+import "github.com/nikgalushko/molekula/internal/parser/testdata/models"
+
+//molekula:bin=login
+type Login struct {
+	Session models.Session `molekula:"session"`
+}
@@ -0,0 +1,221 @@
+package invalid
+
+import "go/token"
+
+//molekula:events
+type Events chan int
+
+//molekula:handlers
+type Handlers map[string]func()
+
+//molekula:entry
+type Record struct {
+	Name     string
+	Position map[token.Position]int
+	Stringer interface{ String() string }
+	Number   complex128
+}
+
+//molekula:tree
+type Tree struct {
+	Children []Tree
+}
+
+//molekula:valid
+type Valid struct {
+	Name string
+}
+
+//molekula:options,numeric=fast
+type Options int
+
+//molekula:tags
+type Tags struct {
+	Number  int      `molekula:",default=abc"`
+	Option  int      `molekula:",optional"`
+	Both    string   `molekula:",required,default=x"`
+	Numbers []int    `molekula:",default=1"`
+	Valid   *float64 `molekula:"valid,omitempty"`
+}
+
+//molekula:rest,unknown=keep
+type Rest struct {
+	Extra  map[string]int         `molekula:",rest"`
+	Other  map[string]interface{} `molekula:",inline"`
+	Second map[string]interface{} `molekula:",rest"`
+	Third  map[string]interface{} `molekula:",rest,required"`
+}
+
+//molekula:embedded
+type Embedded struct {
+	*Valid
+	Base
+	Other
+	TaggedBase `molekula:",omitempty"`
+}
+
+type Base struct {
+	Name string
+}
+
+type Other struct {
+	Title string `molekula:"name"`
+}
+
+type TaggedBase struct {
+	Number int
+}
+
+//molekula:pair
+type Pair struct {
+	A, B   int    `molekula:"a"`
+	secret string `molekula:"s"`
+}
+
+//molekula:group
+type (
+	Grouped int
+)
+
+//molekula:schedule
+type Schedule struct {
+	Every int `molekula:",unix"`
+}
+
+//molekula:half
+type Half struct {
+	Value Decoder
+}
+
+type Decoder int
+
+func (d *Decoder) UnmarshalBin(data interface{}) error {
+	return nil
+}
+
+//molekula:enum
+type Ratio float64
+
+const Half Ratio = 0.5
+
+//molekula:enum
+type Color string
+
+//molekula:palette
+type Palette struct {
+	Ratio  Ratio
+	Colors []Color
+}
+
+//molekula:record
+type Bins []int
+
+//molekula:record
+type Document struct {
+	Title string
+	Extra map[string]interface{} `molekula:",rest"`
+	Bad   complex64
+}
+
+// molekula:,strict
+type Unnamed int
+
+//molekula:much_too_long_name
+type Long int
+
+//molekula:bad.name
+type Dotted int
+
+//molekula:dup
+type DupA int
+
+//molekula:dup
+type DupB int
+
+//molekula:record
+type Clash struct {
+	A     int `molekula:"x"`
+	B     int `molekula:"x"`
+	Größe int
+}
+
+//molekula:bin=odd bin=even
+type Twice int
+
+//molekula:bin=fn func=profile set=
+type Fn int
+
+//molekula:record bin=users
+type Users struct {
+	Name string
+}
+
+//molekula:bin=shared set=a
+type SharedA int
+
+//molekula:bin=shared set=b
+type SharedB int
+
+//molekula:bin=located ns=prod
+type Located int
+
+//molekula:record set=keys
+type Keys struct {
+	Ratio  float64 `molekula:"ratio,key"`
+	ID     string  `molekula:"id,key"`
+	Other  int     `molekula:"other,key"`
+	Nested Valid   `molekula:"nested"`
+	Inner  struct {
+		ID int `molekula:"id,key"`
+	}
+}
+
+//molekula:bin=optional
+type Optional struct {
+	Name string `molekula:",required,omitempty"`
+}
+
+//molekula:bin=overflow
+type Overflow struct {
+	Small int8    `molekula:",default=300"`
+	Tiny  float32 `molekula:",default=1e300"`
+	Count uint16  `molekula:",default=65535"`
+}
+
+//molekula:opaque
+type Opaque struct {
+	Files *token.FileSet
+	Set   FileSet
+}
+
+type FileSet token.FileSet
+
+//molekula:record
+type Camel struct {
+	AB  int `molekula:"a_b"`
+	AB2 int `molekula:"aB"`
+}
+
+//molekula:bin=camel func=DecodeCamelAB
+type Hump int
+
+//molekula:bin=literals
+type Literals struct {
+	On    bool    `molekula:",default=1"`
+	Limit float64 `molekula:",default=inf"`
+}
+
+//molekula:bin=level enum
+type Grade int
+
+const GradeA Grade = 1
+
+//molekula:bin=couple
+type Couple struct {
+	Value Twins `molekula:"value"`
+}
+
+type Twins struct {
+	A int `molekula:"x"`
+	B int `molekula:"x"`
+}
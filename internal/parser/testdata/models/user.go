@@ -0,0 +1,25 @@
+package models
+
+type User struct {
+	Name string `molekula:"n"`
+	Age  int
+}
+
+type Audit struct {
+	Created int64
+	ID      string
+}
+
+//molekula:enum
+type Status string
+
+const (
+	Active  Status = "active"
+	Blocked Status = "blocked"
+	Default        = Active
+)
+
+type Session struct {
+	ID    string
+	token string `molekula:"token"`
+}
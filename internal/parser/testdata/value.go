@@ -0,0 +1,6 @@
+package testdata
+
+type Value struct {
+	Gender string
+	ID     int64
+}
@@ -15,16 +15,41 @@ type Query struct {
 	IsMap     bool
 	IsBuiltin bool
 	IsStruct  bool
+	IsPointer bool
+	// IsFixedArray is true for arrays of Len elements
+	IsFixedArray bool
+	Len          int64
+	// IsBlob is true for byte slices which are stored as blobs
+	IsBlob bool
+	// IsTime is true for time.Time which is stored in the TimeFormat
+	IsTime     bool
+	TimeFormat string
+	// IsDuration is true for time.Duration
+	IsDuration bool
+	// IsCustom is true for types which decode and encode themselves, IsText is true if they use text methods
+	IsCustom bool
+	IsText   bool
 	// Fields is not empty is IsStruct is true
 	Fields []Query
 	// Name is name of struct field
 	Name string
 	// Alias is an alias of struct fields
 	Alias string
+	// OmitEmpty, Required and Default are options of struct field
+	OmitEmpty bool
+	Required  bool
+	Default   string
+	// Rest is true if the struct field collects unknown keys
+	Rest bool
 	// Type is result of call .RawTypeName() function
 	Type string
-	// KeyType is not empty if IsMap is true
+	// Kind is a built-in type of named types like type Level int, it's empty for other types
+	Kind string
+	// Enum is a list of Go literals of allowed values for named types
+	Enum []string
+	// KeyType is not empty if IsMap is true, KeyKind is a built-in type of named key type
 	KeyType string
+	KeyKind string
 	// Next is pointer to description of nested type
 	Next *Query
 }
@@ -32,51 +57,69 @@ type Query struct {
 // Build builds Query from parser.Object for generator.
 // It's naive implementation. It's assumed that the parser.Object is valid and fully complies with the specification.
 func Build(o parser.Object) Query {
-	root := Query{IsTop: true}
-	q := &root
-	t := o.Type
-	index := 0
+	root := build(o.Type, 0)
+	root.IsTop = true
 
-	for {
-		q.Index = index
-		q.Type = t.RawTypeName()
+	return root
+}
 
-		switch kind := t.(type) {
-		case ast.BuiltIn:
-			q.IsBuiltin = true
-			t = nil
-		case ast.Array:
-			q.IsArray = true
-			t = kind.Element
-		case ast.Map:
-			q.IsMap = true
-			q.KeyType = kind.Key.RawTypeName()
-			t = kind.Value
-		case ast.Struct:
-			q.IsStruct = true
-			for _, f := range kind.Fields {
-				q.Fields = append(q.Fields, Query{
-					Name:      f.Name,
-					Alias:     f.Alias,
-					IsBuiltin: true,
-					Index:     index + 1,
-					Type:      f.Type.RawTypeName(),
-				})
-			}
-			t = nil
-		}
+func build(t ast.Type, index int) Query {
+	q := Query{
+		Index: index,
+		Type:  t.RawTypeName(),
+	}
 
-		if t == nil {
-			break
+	switch kind := t.(type) {
+	case ast.BuiltIn:
+		q.IsBuiltin = true
+	case ast.Named:
+		q.IsBuiltin = true
+		q.Kind = kind.Underlying.RawTypeName()
+		q.Enum = kind.Enum
+	case ast.Array:
+		q.IsArray = true
+		next := build(kind.Element, index+1)
+		q.Next = &next
+	case ast.Map:
+		q.IsMap = true
+		q.KeyType = kind.Key.RawTypeName()
+		if key, ok := kind.Key.(ast.Named); ok {
+			q.KeyKind = key.Underlying.RawTypeName()
+		}
+		next := build(kind.Value, index+1)
+		q.Next = &next
+	case ast.Custom:
+		q.IsCustom = true
+		q.IsText = kind.Text
+	case ast.Blob:
+		q.IsBlob = true
+	case ast.Time:
+		q.IsTime = true
+		q.TimeFormat = kind.Format
+	case ast.Duration:
+		q.IsDuration = true
+	case ast.FixedArray:
+		q.IsFixedArray = true
+		q.Len = kind.Len
+		next := build(kind.Element, index+1)
+		q.Next = &next
+	case ast.Pointer:
+		q.IsPointer = true
+		next := build(kind.Element, index+1)
+		q.Next = &next
+	case ast.Struct:
+		q.IsStruct = true
+		for _, f := range kind.Fields {
+			field := build(f.Type, index+1)
+			field.Name = f.Name
+			field.Alias = f.Alias
+			field.OmitEmpty = f.OmitEmpty
+			field.Required = f.Required
+			field.Default = f.Default
+			field.Rest = f.Rest
+			q.Fields = append(q.Fields, field)
 		}
-
-		next := &Query{}
-
-		q.Next = next
-
-		q = next
-		index++
 	}
 
-	return root
+	return q
 }
@@ -220,3 +220,140 @@ func TestBuild_ArrayOfStruct(t *testing.T) {
 		},
 	}, q)
 }
+
+func TestBuild_StructWithNestedFields(t *testing.T) {
+	value := ast.Struct{
+		Name: "Value",
+		Fields: []ast.StructField{
+			{Name: "ID", Alias: "id", Type: ast.BuiltIn("int64")},
+		},
+	}
+
+	q := Build(parser.Object{
+		Type: ast.Struct{
+			Name: "Foo",
+			Fields: []ast.StructField{
+				{
+					Name:  "ArrInt",
+					Alias: "arrint",
+					Type:  ast.Array{Element: ast.BuiltIn("int64")},
+				},
+				{
+					Name:  "Values",
+					Alias: "values",
+					Type:  ast.Map{Key: ast.BuiltIn("string"), Value: value},
+				},
+				{
+					Name:  "Value",
+					Alias: "value",
+					Type:  value,
+				},
+			},
+		},
+	})
+
+	assert.Equal(t, Query{
+		IsTop:    true,
+		IsStruct: true,
+		Type:     "Foo",
+		Fields: []Query{
+			{
+				Name: "ArrInt", Alias: "arrint", Type: "[]int64", Index: 1, IsArray: true,
+				Next: &Query{IsBuiltin: true, Index: 2, Type: "int64"},
+			},
+			{
+				Name: "Values", Alias: "values", Type: "map[string]Value", Index: 1, IsMap: true, KeyType: "string",
+				Next: &Query{
+					IsStruct: true,
+					Index:    2,
+					Type:     "Value",
+					Fields: []Query{
+						{Name: "ID", Alias: "id", Type: "int64", Index: 3, IsBuiltin: true},
+					},
+				},
+			},
+			{
+				Name: "Value", Alias: "value", Type: "Value", Index: 1, IsStruct: true,
+				Fields: []Query{
+					{Name: "ID", Alias: "id", Type: "int64", Index: 2, IsBuiltin: true},
+				},
+			},
+		},
+	}, q)
+}
+
+func TestBuild_FixedArray(t *testing.T) {
+	q := Build(parser.Object{
+		Type: ast.FixedArray{
+			Element: ast.BuiltIn("float64"),
+			Len:     3,
+		},
+	})
+
+	assert.Equal(t, Query{
+		IsTop: true, IsFixedArray: true,
+		Type: "[3]float64",
+		Len:  3,
+		Next: &Query{IsBuiltin: true, Index: 1, Type: "float64"},
+	}, q)
+}
+
+func TestBuild_Blob(t *testing.T) {
+	q := Build(parser.Object{
+		Type: ast.Map{
+			Key:   ast.BuiltIn("string"),
+			Value: ast.Blob("[]byte"),
+		},
+	})
+
+	assert.Equal(t, Query{
+		IsTop: true, IsMap: true,
+		Type:    "map[string][]byte",
+		KeyType: "string",
+		Next:    &Query{IsBlob: true, Index: 1, Type: "[]byte"},
+	}, q)
+}
+
+func TestBuild_Time(t *testing.T) {
+	q := Build(parser.Object{
+		Type: ast.Struct{
+			Name: "Event",
+			Fields: []ast.StructField{
+				{Name: "At", Alias: "at", Type: ast.Time{Format: "unix"}},
+				{Name: "TTL", Alias: "ttl", Type: ast.Duration{}},
+			},
+		},
+	})
+
+	assert.Equal(t, Query{
+		IsTop: true, IsStruct: true,
+		Type: "Event",
+		Fields: []Query{
+			{IsTime: true, Index: 1, Type: "time.Time", TimeFormat: "unix", Name: "At", Alias: "at"},
+			{IsDuration: true, Index: 1, Type: "time.Duration", Name: "TTL", Alias: "ttl"},
+		},
+	}, q)
+}
+
+func TestBuild_Pointer(t *testing.T) {
+	q := Build(parser.Object{
+		Type: ast.Map{
+			Key: ast.BuiltIn("string"),
+			Value: ast.Pointer{
+				Element: ast.BuiltIn("int"),
+			},
+		},
+	})
+
+	assert.Equal(t, Query{
+		IsTop: true, IsMap: true,
+		Type:    "map[string]*int",
+		KeyType: "string",
+		Next: &Query{
+			IsPointer: true,
+			Index:     1,
+			Type:      "*int",
+			Next:      &Query{IsBuiltin: true, Index: 2, Type: "int"},
+		},
+	}, q)
+}
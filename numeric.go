@@ -0,0 +1,103 @@
+package molekula
+
+import (
+	"math"
+	"strconv"
+)
+
+// Int returns a value of any integer type as int64 if it fits into a signed integer of bitSize bits.
+// Bit size 0 means the size of int.
+func Int(v interface{}, bitSize int) (int64, bool) {
+	var i int64
+
+	switch n := v.(type) {
+	case int:
+		i = int64(n)
+	case int8:
+		i = int64(n)
+	case int16:
+		i = int64(n)
+	case int32:
+		i = int64(n)
+	case int64:
+		i = n
+	default:
+		u, ok := Uint(v, 64)
+		if !ok || u > math.MaxInt64 {
+			return 0, false
+		}
+		i = int64(u)
+	}
+
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+
+	if bitSize < 64 {
+		max := int64(1)<<(bitSize-1) - 1
+		if i > max || i < -max-1 {
+			return 0, false
+		}
+	}
+
+	return i, true
+}
+
+// Uint returns a value of any integer type as uint64 if it fits into an unsigned integer of bitSize bits.
+// Bit size 0 means the size of uint.
+func Uint(v interface{}, bitSize int) (uint64, bool) {
+	var u uint64
+
+	switch n := v.(type) {
+	case uint:
+		u = uint64(n)
+	case uint8:
+		u = uint64(n)
+	case uint16:
+		u = uint64(n)
+	case uint32:
+		u = uint64(n)
+	case uint64:
+		u = n
+	case uintptr:
+		u = uint64(n)
+	case int, int8, int16, int32, int64:
+		i, _ := Int(n, 64)
+		if i < 0 {
+			return 0, false
+		}
+		u = uint64(i)
+	default:
+		return 0, false
+	}
+
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+
+	if bitSize < 64 && u > uint64(1)<<bitSize-1 {
+		return 0, false
+	}
+
+	return u, true
+}
+
+// Float returns a value of float32 or float64 type as float64 if it fits into a float of bitSize bits.
+func Float(v interface{}, bitSize int) (float64, bool) {
+	var f float64
+
+	switch n := v.(type) {
+	case float32:
+		f = float64(n)
+	case float64:
+		f = n
+	default:
+		return 0, false
+	}
+
+	if bitSize == 32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+		return 0, false
+	}
+
+	return f, true
+}
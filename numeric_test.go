@@ -0,0 +1,76 @@
+package molekula
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInt(t *testing.T) {
+	tests := map[string]struct {
+		value   interface{}
+		bitSize int
+		ret     int64
+		ok      bool
+	}{
+		"int to int64":           {value: 42, bitSize: 64, ret: 42, ok: true},
+		"int64 to int":           {value: int64(-42), bitSize: 0, ret: -42, ok: true},
+		"uint8 to int16":         {value: uint8(200), bitSize: 16, ret: 200, ok: true},
+		"int to int8 overflow":   {value: 128, bitSize: 8, ok: false},
+		"int to int8 underflow":  {value: -129, bitSize: 8, ok: false},
+		"int8 min":               {value: -128, bitSize: 8, ret: -128, ok: true},
+		"uint64 overflow":        {value: uint64(math.MaxUint64), bitSize: 64, ok: false},
+		"float is not converted": {value: 1.0, bitSize: 64, ok: false},
+		"string":                 {value: "1", bitSize: 64, ok: false},
+	}
+
+	for title, tt := range tests {
+		ret, ok := Int(tt.value, tt.bitSize)
+		assert.Equal(t, tt.ok, ok, title)
+		assert.Equal(t, tt.ret, ret, title)
+	}
+}
+
+func TestUint(t *testing.T) {
+	tests := map[string]struct {
+		value   interface{}
+		bitSize int
+		ret     uint64
+		ok      bool
+	}{
+		"int to uint":           {value: 42, bitSize: 0, ret: 42, ok: true},
+		"uint64 max":            {value: uint64(math.MaxUint64), bitSize: 64, ret: math.MaxUint64, ok: true},
+		"negative int":          {value: -1, bitSize: 64, ok: false},
+		"int to uint8":          {value: 255, bitSize: 8, ret: 255, ok: true},
+		"int to uint8 overflow": {value: 256, bitSize: 8, ok: false},
+		"nil":                   {value: nil, bitSize: 8, ok: false},
+	}
+
+	for title, tt := range tests {
+		ret, ok := Uint(tt.value, tt.bitSize)
+		assert.Equal(t, tt.ok, ok, title)
+		assert.Equal(t, tt.ret, ret, title)
+	}
+}
+
+func TestFloat(t *testing.T) {
+	tests := map[string]struct {
+		value   interface{}
+		bitSize int
+		ret     float64
+		ok      bool
+	}{
+		"float64 to float32":          {value: 0.5, bitSize: 32, ret: 0.5, ok: true},
+		"float32 to float64":          {value: float32(0.5), bitSize: 64, ret: 0.5, ok: true},
+		"float64 to float32 overflow": {value: math.MaxFloat64, bitSize: 32, ok: false},
+		"infinity":                    {value: math.Inf(1), bitSize: 32, ret: math.Inf(1), ok: true},
+		"int is not converted":        {value: 1, bitSize: 64, ok: false},
+	}
+
+	for title, tt := range tests {
+		ret, ok := Float(tt.value, tt.bitSize)
+		assert.Equal(t, tt.ok, ok, title)
+		assert.Equal(t, tt.ret, ret, title)
+	}
+}